@@ -5,7 +5,8 @@ go 1.16
 require (
 	github.com/diamondburned/arikawa/v3 v3.2.0
 	github.com/gorilla/schema v1.2.0
-	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/gorilla/websocket v1.5.0
 	github.com/stretchr/testify v1.8.1
-	golang.org/x/time v0.3.0 // indirect
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
+	golang.org/x/time v0.3.0
 )
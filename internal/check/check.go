@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
 	"github.com/diamondburned/arikawa/v3/utils/sendpart"
@@ -132,11 +133,48 @@ func Query(t testing.TInterface, expect url.Values, actual url.Values) {
 	}
 }
 
-// Header checks if the expected http.Header are contained in actual.
-func Header(t testing.TInterface, expect http.Header, actual http.Header) {
-	for _, expect := range expect {
-		assert.Contains(t, actual, expect)
+// maxAuditLogReasonLen is the maximum number of characters an audit log
+// reason may have.
+const maxAuditLogReasonLen = 512
+
+// AuditLogReason checks if the X-Audit-Log-Reason header in actual strictly
+// matches the one in expect, as returned by api.AuditLogReason.Header.
+//
+// If expect contains no reason, the header must be absent in actual.
+// Otherwise, actual must contain exactly one URL-encoded reason of at most 512
+// characters, that matches the expected reason when decoded.
+func AuditLogReason(t testing.TInterface, expect http.Header, actual http.Header) {
+	const name = "X-Audit-Log-Reason"
+
+	expectReason := expect.Get(name)
+	actualVals := actual.Values(name)
+
+	if expectReason == "" {
+		assert.Emptyf(t, actualVals, "unexpected %s header", name)
+		return
+	}
+
+	if !assert.Lenf(t, actualVals, 1, "expected exactly one %s header", name) {
+		return
 	}
+
+	for _, r := range actualVals[0] {
+		if r < ' ' || r > '~' {
+			assert.Failf(t, "invalid "+name+" header",
+				"header contains the non-ASCII character %q and is therefore not URL-encoded: %q", r, actualVals[0])
+			return
+		}
+	}
+
+	actualReason, err := url.PathUnescape(actualVals[0])
+	if !assert.NoErrorf(t, err, "%s header is not URL-encoded correctly", name) {
+		return
+	}
+
+	assert.LessOrEqualf(t, utf8.RuneCountInString(actualReason), maxAuditLogReasonLen,
+		"audit log reason exceeds the limit of %d characters", maxAuditLogReasonLen)
+
+	assert.Equal(t, expectReason, actualReason, "audit log reasons don't match")
 }
 
 // checkJSON checks if body contains the JSON data matching the passed expected
@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
@@ -462,6 +463,111 @@ func TestCheckQuery(t *testing.T) {
 	})
 }
 
+func TestAuditLogReason(t *testing.T) {
+	header := func(vals ...string) http.Header {
+		h := make(http.Header)
+
+		for _, val := range vals {
+			h.Add("X-Audit-Log-Reason", val)
+		}
+
+		return h
+	}
+
+	successCases := []struct {
+		name   string
+		expect http.Header
+		actual http.Header
+	}{
+		{
+			name:   "no reason",
+			expect: nil,
+			actual: header(),
+		},
+		{
+			name:   "ascii",
+			expect: header("abc def"),
+			actual: header("abc def"),
+		},
+		{
+			name:   "url-encoded",
+			expect: header("äbc def"),
+			actual: header("%C3%A4bc%20def"),
+		},
+		{
+			name:   "max length",
+			expect: header(strings.Repeat("ä", 512)),
+			actual: header(strings.Repeat("%C3%A4", 512)),
+		},
+	}
+
+	failureCases := []struct {
+		name   string
+		expect http.Header
+		actual http.Header
+	}{
+		{
+			name:   "unexpected reason",
+			expect: nil,
+			actual: header("abc"),
+		},
+		{
+			name:   "missing reason",
+			expect: header("abc"),
+			actual: header(),
+		},
+		{
+			name:   "multiple reasons",
+			expect: header("abc"),
+			actual: header("abc", "abc"),
+		},
+		{
+			name:   "unequal",
+			expect: header("abc"),
+			actual: header("def"),
+		},
+		{
+			name:   "not url-encoded",
+			expect: header("äbc"),
+			actual: header("äbc"),
+		},
+		{
+			name:   "invalid url-encoding",
+			expect: header("50% off"),
+			actual: header("50% off"),
+		},
+		{
+			name:   "too long",
+			expect: header(strings.Repeat("a", 513)),
+			actual: header(strings.Repeat("a", 513)),
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		for _, c := range successCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				AuditLogReason(t, c.expect, c.actual)
+			})
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		for _, c := range failureCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				tMock := new(testing.T)
+
+				AuditLogReason(tMock, c.expect, c.actual)
+
+				assert.True(t, tMock.Failed())
+			})
+		}
+	})
+}
+
 func Test_replaceNullables(t *testing.T) {
 	testCases := []struct {
 		name   string
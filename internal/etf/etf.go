@@ -0,0 +1,553 @@
+// Package etf provides conversion between JSON and Erlang's External Term
+// Format, as used by Discord's gateway when connecting with encoding=etf.
+package etf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// version is the version byte that precedes every term.
+const version = 131
+
+// Tags of the terms supported.
+const (
+	newFloatExt      = 70
+	compressedExt    = 80
+	smallIntegerExt  = 97
+	integerExt       = 98
+	floatExt         = 99
+	atomExt          = 100
+	smallTupleExt    = 104
+	largeTupleExt    = 105
+	nilExt           = 106
+	stringExt        = 107
+	listExt          = 108
+	binaryExt        = 109
+	smallBigExt      = 110
+	largeBigExt      = 111
+	smallAtomExt     = 115
+	mapExt           = 116
+	atomUTF8Ext      = 118
+	smallAtomUTF8Ext = 119
+)
+
+// ErrInvalidTerm is returned, if the data passed to ToJSON is not a valid
+// term.
+var ErrInvalidTerm = errors.New("etf: invalid term")
+
+// FromJSON converts the passed JSON data to ETF.
+//
+// Like Discord, it encodes null, true and false as atoms, object keys as
+// atoms, snowflakes as integers, and all other strings as binaries.
+// Snowflakes are the strings of the id and *_id fields, and of the
+// elements of *_ids fields, that are valid snowflakes.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(version)
+
+	if err := encode(&b, v, ""); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// encode writes the passed value, as decoded by encoding/json, to b.
+// key is the key of the object field the value belongs to, or, if the
+// value is an array element, the key of the array.
+func encode(b *bytes.Buffer, v interface{}, key string) error {
+	switch v := v.(type) {
+	case nil:
+		writeAtom(b, "nil")
+	case bool:
+		writeAtom(b, strconv.FormatBool(v))
+	case string:
+		if isSnowflakeKey(key) && isSnowflake(v) {
+			return encodeNumber(b, json.Number(v))
+		}
+
+		b.WriteByte(binaryExt)
+		writeUint32(b, uint32(len(v)))
+		b.WriteString(v)
+	case json.Number:
+		return encodeNumber(b, v)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteByte(nilExt)
+			return nil
+		}
+
+		b.WriteByte(listExt)
+		writeUint32(b, uint32(len(v)))
+
+		for _, elem := range v {
+			if err := encode(b, elem, key); err != nil {
+				return err
+			}
+		}
+
+		b.WriteByte(nilExt)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		b.WriteByte(mapExt)
+		writeUint32(b, uint32(len(v)))
+
+		for _, k := range keys {
+			writeAtom(b, k)
+
+			if err := encode(b, v[k], k); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("etf: unsupported type %T", v)
+	}
+
+	return nil
+}
+
+// nonSnowflakeKeys are the keys matched by isSnowflakeKey, that aren't
+// snowflakes.
+var nonSnowflakeKeys = map[string]struct{}{
+	"session_id": {},
+	"custom_id":  {},
+}
+
+// isSnowflakeKey checks if the field with the passed key contains
+// snowflakes.
+func isSnowflakeKey(key string) bool {
+	if _, ok := nonSnowflakeKeys[key]; ok {
+		return false
+	}
+
+	return key == "id" || strings.HasSuffix(key, "_id") || strings.HasSuffix(key, "_ids")
+}
+
+// isSnowflake checks if the passed string is a snowflake, i.e. a positive
+// decimal 64 bit integer without leading zeros.
+func isSnowflake(s string) bool {
+	if s == "" || s[0] == '0' {
+		return false
+	}
+
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// encodeNumber writes the passed number to b, using the smallest fitting
+// term.
+func encodeNumber(b *bytes.Buffer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		switch {
+		case i >= 0 && i <= math.MaxUint8:
+			b.WriteByte(smallIntegerExt)
+			b.WriteByte(byte(i))
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			b.WriteByte(integerExt)
+			writeUint32(b, uint32(int32(i)))
+		default:
+			writeBig(b, big.NewInt(i))
+		}
+
+		return nil
+	}
+
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		writeBig(b, i)
+		return nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+
+	b.WriteByte(newFloatExt)
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], math.Float64bits(f))
+	b.Write(buf[:])
+
+	return nil
+}
+
+// writeBig writes the passed integer as small big.
+func writeBig(b *bytes.Buffer, i *big.Int) {
+	digits := i.Bytes() // big endian
+
+	b.WriteByte(smallBigExt)
+	b.WriteByte(byte(len(digits)))
+
+	if i.Sign() < 0 {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+
+	for j := len(digits) - 1; j >= 0; j-- {
+		b.WriteByte(digits[j])
+	}
+}
+
+// writeAtom writes the passed atom to b.
+func writeAtom(b *bytes.Buffer, atom string) {
+	if len(atom) <= math.MaxUint8 {
+		b.WriteByte(smallAtomUTF8Ext)
+		b.WriteByte(byte(len(atom)))
+	} else {
+		b.WriteByte(atomUTF8Ext)
+
+		var buf [2]byte
+		binary.BigEndian.PutUint16(buf[:], uint16(len(atom)))
+		b.Write(buf[:])
+	}
+
+	b.WriteString(atom)
+}
+
+// writeUint32 writes the passed big endian uint32 to b.
+func writeUint32(b *bytes.Buffer, i uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], i)
+	b.Write(buf[:])
+}
+
+// ToJSON converts the passed ETF data to JSON.
+//
+// Atoms other than nil, true and false are converted to strings, as are
+// map keys.
+// Tuples and lists are both converted to arrays.
+func ToJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != version {
+		return nil, ErrInvalidTerm
+	}
+
+	d := decoder{data: data[1:]}
+
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.data) > 0 {
+		return nil, ErrInvalidTerm
+	}
+
+	return json.Marshal(v)
+}
+
+// decoder decodes terms.
+type decoder struct {
+	// data is the data that is yet to be decoded.
+	data []byte
+}
+
+// decode decodes the next term.
+func (d *decoder) decode() (interface{}, error) {
+	tag, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag[0] {
+	case smallIntegerExt:
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+
+		return int64(b[0]), nil
+	case integerExt:
+		i, err := d.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		return int64(int32(i)), nil
+	case smallBigExt, largeBigExt:
+		return d.decodeBig(tag[0])
+	case newFloatExt:
+		i, err := d.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(i), nil
+	case floatExt:
+		b, err := d.read(31)
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.ParseFloat(string(bytes.TrimRight(b, "\x00")), 64)
+	case atomExt, atomUTF8Ext:
+		return d.decodeAtom(2)
+	case smallAtomExt, smallAtomUTF8Ext:
+		return d.decodeAtom(1)
+	case smallTupleExt:
+		return d.decodeElems(1, false)
+	case largeTupleExt:
+		return d.decodeElems(4, false)
+	case nilExt:
+		return []interface{}{}, nil
+	case stringExt:
+		n, err := d.readUint(2)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]interface{}, len(b))
+		for i, c := range b {
+			list[i] = int64(c)
+		}
+
+		return list, nil
+	case listExt:
+		return d.decodeElems(4, true)
+	case binaryExt:
+		n, err := d.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	case mapExt:
+		return d.decodeMap()
+	case compressedExt:
+		return d.decodeCompressed()
+	default:
+		return nil, fmt.Errorf("etf: unsupported tag %d", tag[0])
+	}
+}
+
+// decodeBig decodes a small or large big.
+func (d *decoder) decodeBig(tag byte) (interface{}, error) {
+	size := 1
+	if tag == largeBigExt {
+		size = 4
+	}
+
+	n, err := d.readUint(size)
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	digits, err := d.read(int(n))
+	if err != nil {
+		return nil, err
+	}
+
+	bigEndian := make([]byte, len(digits))
+	for i, digit := range digits {
+		bigEndian[len(digits)-1-i] = digit
+	}
+
+	i := new(big.Int).SetBytes(bigEndian)
+	if sign[0] != 0 {
+		i.Neg(i)
+	}
+
+	return json.Number(i.String()), nil
+}
+
+// decodeAtom decodes an atom, whose length is stored in size bytes.
+func (d *decoder) decodeAtom(size int) (interface{}, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := d.read(int(n))
+	if err != nil {
+		return nil, err
+	}
+
+	switch atom := string(b); atom {
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return atom, nil
+	}
+}
+
+// decodeElems decodes the elements of a tuple or list, whose length is
+// stored in size bytes.
+// If list is true, the tail of the list is decoded as well.
+func (d *decoder) decodeElems(size int, list bool) (interface{}, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return nil, err
+	}
+
+	// every element is at least one byte long
+	if n > uint64(len(d.data)) {
+		return nil, ErrInvalidTerm
+	}
+
+	elems := make([]interface{}, n)
+	for i := range elems {
+		if elems[i], err = d.decode(); err != nil {
+			return nil, err
+		}
+	}
+
+	if list {
+		tail, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		// improper lists are converted to arrays containing their tail
+		if tail, ok := tail.([]interface{}); !ok || len(tail) > 0 {
+			elems = append(elems, tail)
+		}
+	}
+
+	return elems, nil
+}
+
+// decodeMap decodes a map.
+func (d *decoder) decodeMap() (interface{}, error) {
+	n, err := d.readUint(4)
+	if err != nil {
+		return nil, err
+	}
+
+	// every key and value is at least one byte long
+	if n > uint64(len(d.data))/2 {
+		return nil, ErrInvalidTerm
+	}
+
+	m := make(map[string]interface{}, n)
+
+	for i := uint64(0); i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if s, ok := k.(string); ok {
+			m[s] = v
+		} else {
+			m[fmt.Sprint(k)] = v
+		}
+	}
+
+	return m, nil
+}
+
+// decodeCompressed decodes a zlib compressed term.
+func (d *decoder) decodeCompressed() (interface{}, error) {
+	n, err := d.readUint(4)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(d.data))
+	if err != nil {
+		return nil, err
+	}
+
+	// the uncompressed size is sent by the client, so don't allocate it
+	// upfront, but read at most one byte more to detect a size mismatch
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(n)+1))
+	if err != nil {
+		return nil, err
+	} else if uint64(len(data)) != n {
+		return nil, ErrInvalidTerm
+	}
+
+	if err := r.Close(); err != nil {
+		return nil, err
+	}
+
+	compressed := decoder{data: data}
+
+	v, err := compressed.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	// compressed terms are always top-level terms, so there is no data
+	// after them
+	d.data = nil
+
+	return v, nil
+}
+
+// read reads the next n bytes.
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data) < n {
+		return nil, ErrInvalidTerm
+	}
+
+	b := d.data[:n]
+	d.data = d.data[n:]
+
+	return b, nil
+}
+
+// readUint reads a big endian unsigned integer of the passed size.
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+
+	var i uint64
+	for _, c := range b {
+		i = i<<8 | uint64(c)
+	}
+
+	return i, nil
+}
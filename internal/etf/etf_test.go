@@ -0,0 +1,198 @@
+package etf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromJSON(t *testing.T) {
+	testCases := []struct {
+		name   string
+		json   string
+		expect []byte
+	}{
+		{name: "null", json: "null", expect: []byte{131, 119, 3, 'n', 'i', 'l'}},
+		{name: "true", json: "true", expect: []byte{131, 119, 4, 't', 'r', 'u', 'e'}},
+		{name: "string", json: `"ab"`, expect: []byte{131, 109, 0, 0, 0, 2, 'a', 'b'}},
+		{name: "small integer", json: "12", expect: []byte{131, 97, 12}},
+		{name: "integer", json: "-1", expect: []byte{131, 98, 255, 255, 255, 255}},
+		{
+			name:   "big",
+			json:   "18446744073709551615",
+			expect: []byte{131, 110, 8, 0, 255, 255, 255, 255, 255, 255, 255, 255},
+		},
+		{name: "float", json: "1.5", expect: []byte{131, 70, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{name: "empty list", json: "[]", expect: []byte{131, 106}},
+		{name: "list", json: "[1,2]", expect: []byte{131, 108, 0, 0, 0, 2, 97, 1, 97, 2, 106}},
+		{
+			name:   "map",
+			json:   `{"op":1}`,
+			expect: []byte{131, 116, 0, 0, 0, 1, 119, 2, 'o', 'p', 97, 1},
+		},
+		{
+			name:   "snowflake",
+			json:   `{"id":"1099511627776"}`,
+			expect: []byte{131, 116, 0, 0, 0, 1, 119, 2, 'i', 'd', 110, 6, 0, 0, 0, 0, 0, 0, 1},
+		},
+		{
+			name: "snowflake array",
+			json: `{"role_ids":["1"]}`,
+			expect: []byte{
+				131, 116, 0, 0, 0, 1,
+				119, 8, 'r', 'o', 'l', 'e', '_', 'i', 'd', 's', 108, 0, 0, 0, 1, 97, 1, 106,
+			},
+		},
+		{
+			name: "session id",
+			json: `{"session_id":"1"}`,
+			expect: []byte{
+				131, 116, 0, 0, 0, 1,
+				119, 10, 's', 'e', 's', 's', 'i', 'o', 'n', '_', 'i', 'd', 109, 0, 0, 0, 1, '1',
+			},
+		},
+		{
+			name:   "non-snowflake id",
+			json:   `{"id":"01"}`,
+			expect: []byte{131, 116, 0, 0, 0, 1, 119, 2, 'i', 'd', 109, 0, 0, 0, 2, '0', '1'},
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := FromJSON([]byte(c.json))
+			require.NoError(t, err)
+			assert.Equal(t, c.expect, actual)
+		})
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			etf    []byte
+			expect string
+		}{
+			{name: "atom", etf: []byte{131, 100, 0, 3, 'a', 'b', 'c'}, expect: `"abc"`},
+			{name: "false", etf: []byte{131, 115, 5, 'f', 'a', 'l', 's', 'e'}, expect: "false"},
+			{name: "string", etf: []byte{131, 107, 0, 2, 1, 2}, expect: "[1,2]"},
+			{name: "tuple", etf: []byte{131, 104, 2, 97, 1, 97, 2}, expect: "[1,2]"},
+			{name: "negative big", etf: []byte{131, 110, 1, 1, 5}, expect: "-5"},
+			{
+				name:   "round trip",
+				etf:    mustFromJSON(t, `{"d":{"a":[true,null,"b"],"c":123456789012},"op":0}`),
+				expect: `{"d":{"a":[true,null,"b"],"c":123456789012},"op":0}`,
+			},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				actual, err := ToJSON(c.etf)
+				require.NoError(t, err)
+				assert.JSONEq(t, c.expect, string(actual))
+			})
+		}
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		term := mustFromJSON(t, `{"op":1}`)[1:]
+
+		data := append([]byte{131, 80, 0, 0, 0, byte(len(term))}, compress(t, term)...)
+
+		actual, err := ToJSON(data)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"op":1}`, string(actual))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			etf  []byte
+		}{
+			{name: "empty", etf: nil},
+			{name: "wrong version", etf: []byte{130, 97, 1}},
+			{name: "truncated", etf: []byte{131, 109, 0, 0, 0, 2, 'a'}},
+			{name: "trailing data", etf: []byte{131, 97, 1, 97}},
+			{name: "unsupported tag", etf: []byte{131, 1}},
+			{name: "list too long", etf: []byte{131, 108, 0x7f, 0xff, 0xff, 0xff}},
+			{name: "tuple too long", etf: []byte{131, 105, 0x7f, 0xff, 0xff, 0xff, 97, 1}},
+			{name: "map too long", etf: []byte{131, 116, 0x7f, 0xff, 0xff, 0xff, 97, 1}},
+			{
+				name: "compressed size mismatch",
+				etf:  append([]byte{131, 80, 0x7f, 0xff, 0xff, 0xff}, compress(t, []byte{97, 1})...),
+			},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				_, err := ToJSON(c.etf)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+// Discord sends snowflakes as integers, which arikawa decodes from the JSON
+// the ETF is converted to.
+func TestFromJSON_snowflakes(t *testing.T) {
+	expect := discord.Message{
+		ID:        1 << 40,
+		ChannelID: 2,
+		GuildID:   3 << 40,
+		Author:    discord.User{ID: 4},
+		Content:   "123",
+	}
+
+	data, err := json.Marshal(expect)
+	require.NoError(t, err)
+
+	data, err = FromJSON(data)
+	require.NoError(t, err)
+
+	data, err = ToJSON(data)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":1099511627776`)
+
+	var actual discord.Message
+	require.NoError(t, json.Unmarshal(data, &actual))
+
+	assert.Equal(t, expect.ID, actual.ID)
+	assert.Equal(t, expect.ChannelID, actual.ChannelID)
+	assert.Equal(t, expect.GuildID, actual.GuildID)
+	assert.Equal(t, expect.Author.ID, actual.Author.ID)
+	assert.Equal(t, expect.Content, actual.Content)
+}
+
+// compress compresses the passed data using zlib.
+func compress(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return b.Bytes()
+}
+
+func mustFromJSON(t *testing.T, json string) []byte {
+	t.Helper()
+
+	data, err := FromJSON([]byte(json))
+	require.NoError(t, err)
+
+	return data
+}
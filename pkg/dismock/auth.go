@@ -0,0 +1,103 @@
+package dismock
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+// TokenType is the type of the token used in the Authorization header.
+type TokenType string
+
+const (
+	// BotToken is the type of the token of a bot user.
+	BotToken TokenType = "Bot"
+	// BearerToken is the type of an OAuth2 access token.
+	BearerToken TokenType = "Bearer"
+)
+
+// unauthorizedError is the error Discord responds with, if the Authorization
+// header is missing or invalid.
+var unauthorizedError = httputil.HTTPError{
+	Status:  http.StatusUnauthorized,
+	Code:    0,
+	Message: "401: Unauthorized",
+}
+
+// tokenExemptPath matches the paths of all endpoints that are authorized
+// through a webhook or interaction token in their path, instead of the
+// Authorization header.
+var tokenExemptPath = regexp.MustCompile(`^/api/v\d+/(?:webhooks|interactions)/\d+/[^/]+(?:/|$)`)
+
+// apiPath matches the paths of all API endpoints.
+var apiPath = regexp.MustCompile(`^/api/v\d+/`)
+
+// apiRoute matches the requests to API endpoints with a specific method and
+// path.
+type apiRoute struct {
+	method string
+	// path are the segments of the path, following the api version.
+	// A '*' matches any segment.
+	path []string
+}
+
+// newAPIRoute creates a new apiRoute matching the passed method and path.
+// path is the path following the api version, and may use '*' to match any
+// segment.
+func newAPIRoute(method, path string) apiRoute {
+	return apiRoute{method: method, path: strings.Split(path, "/")}
+}
+
+// matches checks if the route matches the passed method and path segments.
+func (r apiRoute) matches(method string, segments []string) bool {
+	if r.method != method || len(r.path) != len(segments) {
+		return false
+	}
+
+	for i, s := range r.path {
+		if s != "*" && s != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apiSegments returns the segments of the path of the passed request to an
+// API endpoint, following the api version.
+func apiSegments(r *http.Request) []string {
+	return strings.Split(apiPath.ReplaceAllString(strings.TrimRight(r.URL.EscapedPath(), "/"), ""), "/")
+}
+
+// WithToken makes the Mocker require the passed token on all requests made
+// to the API.
+// Requests with a missing or wrong Authorization header will be answered
+// with Discord's 401 Unauthorized error, and the mock for the request will
+// stay queued up.
+//
+// Endpoints that are authorized by a webhook or interaction token in their
+// path, as well as requests for metadata, are exempt.
+//
+// Sessions and states created by the Mocker will use the passed token.
+func WithToken(typ TokenType, token string) Option {
+	return func(m *Mocker) {
+		m.token = string(typ) + " " + token
+	}
+}
+
+// authorized checks if the passed request is authorized, if the Mocker
+// requires a token.
+func (m *Mocker) authorized(r *http.Request) bool {
+	if m.token == "" {
+		return true
+	}
+
+	path := r.URL.EscapedPath()
+	if !apiPath.MatchString(path) || tokenExemptPath.MatchString(path) {
+		return true
+	}
+
+	return r.Header.Get("Authorization") == m.token
+}
@@ -0,0 +1,110 @@
+package dismock
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithToken(t *testing.T) {
+	t.Run("session", func(t *testing.T) {
+		m, s := NewSession(t, WithToken(BotToken, "abc"))
+
+		expect := discord.Channel{ID: 123, VideoQualityMode: discord.AutoVideoQuality}
+		m.Channel(expect)
+
+		actual, err := s.Channel(123)
+		require.NoError(t, err)
+
+		assert.Equal(t, expect, *actual)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			header string
+		}{
+			{name: "missing", header: ""},
+			{name: "wrong token", header: "Bot def"},
+			{name: "wrong type", header: "Bearer abc"},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m := New(t, WithToken(BotToken, "abc"))
+				m.Channel(discord.Channel{ID: 123})
+
+				req, err := http.NewRequest(http.MethodGet,
+					"https://"+m.Server.Listener.Addr().String()+"/api/v9/channels/123", nil)
+				require.NoError(t, err)
+
+				if c.header != "" {
+					req.Header.Set("Authorization", c.header)
+				}
+
+				resp, err := m.Client.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+				var actual httputil.HTTPError
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+
+				assert.Equal(t, unauthorizedError.Code, actual.Code)
+				assert.Equal(t, unauthorizedError.Message, actual.Message)
+
+				assert.Len(t, m.handlers, 1, "handler was removed")
+
+				m.Close() // prevent m.eval from failing
+			})
+		}
+	})
+
+	t.Run("exempt", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			path string
+		}{
+			{name: "webhook", path: "/api/v9/webhooks/123/abc"},
+			{name: "webhook message", path: "/api/v9/webhooks/123/abc/messages/456"},
+			{name: "interaction", path: "/api/v9/interactions/123/abc/callback"},
+			{name: "meta", path: "/icons/123/abc.png"},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m := New(t, WithToken(BotToken, "abc"))
+				m.Mock("test", http.MethodGet, c.path[1:], nil)
+
+				resp, err := m.Client.Get("https://" + m.Server.Listener.Addr().String() + c.path)
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+
+				assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+			})
+		}
+	})
+
+	t.Run("webhook without token", func(t *testing.T) {
+		m := New(t, WithToken(BotToken, "abc"))
+		m.Mock("test", http.MethodGet, "api/v9/webhooks/123", nil)
+
+		resp, err := m.Client.Get("https://" + m.Server.Listener.Addr().String() + "/api/v9/webhooks/123")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		m.Close() // prevent m.eval from failing
+	})
+}
@@ -0,0 +1,54 @@
+package dismock
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state/store"
+	"github.com/stretchr/testify/require"
+)
+
+// CacheGuild stores the passed guild in the cache of the state created by
+// NewCachedState.
+func (m *Mocker) CacheGuild(g discord.Guild) {
+	m.cache(func(c *store.Cabinet) error { return c.GuildSet(&g, false) })
+}
+
+// CacheChannel stores the passed channel in the cache of the state created
+// by NewCachedState.
+func (m *Mocker) CacheChannel(c discord.Channel) {
+	m.cache(func(cab *store.Cabinet) error { return cab.ChannelSet(&c, false) })
+}
+
+// CacheMember stores the passed member of the guild with the passed id in
+// the cache of the state created by NewCachedState.
+func (m *Mocker) CacheMember(guildID discord.GuildID, member discord.Member) {
+	m.cache(func(c *store.Cabinet) error { return c.MemberSet(guildID, &member, false) })
+}
+
+// CacheRole stores the passed role of the guild with the passed id in the
+// cache of the state created by NewCachedState.
+func (m *Mocker) CacheRole(guildID discord.GuildID, r discord.Role) {
+	m.cache(func(c *store.Cabinet) error { return c.RoleSet(guildID, &r, false) })
+}
+
+// CacheMessage stores the passed message in the cache of the state created
+// by NewCachedState.
+//
+// Like the state, the cache keeps at most the 100 latest messages of a
+// channel.
+func (m *Mocker) CacheMessage(msg discord.Message) {
+	m.cache(func(c *store.Cabinet) error { return c.MessageSet(&msg, false) })
+}
+
+// cache stores an entry in the cache of the state created by
+// NewCachedState, using the passed function.
+// The function is remembered, so that clones created using
+// Mocker.CloneState start with the same cache.
+func (m *Mocker) cache(set func(c *store.Cabinet) error) {
+	if m.cabinet == nil {
+		m.t.Fatal("dismock: the Cache methods require a Mocker created using NewCachedState")
+		return
+	}
+
+	m.cached = append(m.cached, set)
+	require.NoError(m.t, set(m.cabinet))
+}
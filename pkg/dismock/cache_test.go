@@ -0,0 +1,109 @@
+package dismock
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCachedState(t *testing.T) {
+	t.Run("cache miss", func(t *testing.T) {
+		m, s := NewCachedState(t)
+
+		expect := discord.Guild{ID: 123, Name: "abc"}
+
+		m.Guild(expect)
+
+		actual, err := s.Guild(expect.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, expect.Name, actual.Name)
+
+		// the guild is now cached, so no second request is made
+		actual, err = s.Guild(expect.ID)
+		require.NoError(t, err)
+
+		assert.Equal(t, expect.Name, actual.Name)
+	})
+
+	t.Run("uncached request", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		_, s := NewCachedState(tMock)
+
+		_, err := s.Guild(123)
+		assert.Error(t, err)
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestMocker_CacheGuild(t *testing.T) {
+	m, s := NewCachedState(t)
+
+	expect := discord.Guild{ID: 123, Name: "abc"}
+
+	m.CacheGuild(expect)
+
+	actual, err := s.Guild(expect.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, expect, *actual)
+}
+
+func TestMocker_CacheChannel(t *testing.T) {
+	m, s := NewCachedState(t)
+
+	expect := discord.Channel{ID: 123, GuildID: 456, Name: "abc"}
+
+	m.CacheChannel(expect)
+
+	actual, err := s.Channel(expect.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, expect, *actual)
+}
+
+func TestMocker_CacheMember(t *testing.T) {
+	m, s := NewCachedState(t)
+
+	var guildID discord.GuildID = 123
+
+	expect := discord.Member{User: discord.User{ID: 456, Username: "abc"}, Nick: "def"}
+
+	m.CacheMember(guildID, expect)
+
+	actual, err := s.Member(guildID, expect.User.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, expect, *actual)
+}
+
+func TestMocker_CacheRole(t *testing.T) {
+	m, s := NewCachedState(t)
+
+	var guildID discord.GuildID = 123
+
+	expect := discord.Role{ID: 456, Name: "abc"}
+
+	m.CacheRole(guildID, expect)
+
+	actual, err := s.Role(guildID, expect.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, expect, *actual)
+}
+
+func TestMocker_CacheMessage(t *testing.T) {
+	m, s := NewCachedState(t)
+
+	expect := discord.Message{ID: 123, ChannelID: 456, Content: "abc"}
+
+	m.CacheMessage(expect)
+
+	actual, err := s.Message(expect.ChannelID, expect.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, expect, *actual)
+}
@@ -0,0 +1,158 @@
+package dismock
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Chaos configures the chaos mode of a Mocker, in which responses of mocks
+// are randomly replaced by rate limits, server errors or dropped
+// connections, or delayed by latency spikes.
+//
+// The rates are probabilities between 0 and 1, and must not add up to more
+// than 1.
+type Chaos struct {
+	// Seed is the seed used to generate the random failures.
+	// If it is 0, the current time is used as seed.
+	//
+	// The seed is logged, if the test fails, so that the failure can be
+	// reproduced.
+	// Note however, that requests made concurrently may be affected in a
+	// different order, every time the test is run.
+	Seed int64
+
+	// RateLimitRate is the probability with which a 429 Too Many Requests is
+	// sent.
+	RateLimitRate float64
+	// ServerErrorRate is the probability with which a 500, 502, 503 or 504
+	// is sent.
+	ServerErrorRate float64
+	// DropRate is the probability with which the connection is closed without
+	// sending a response.
+	DropRate float64
+	// LatencyRate is the probability with which the response is delayed by
+	// Latency.
+	LatencyRate float64
+	// Latency is the duration of a latency spike.
+	// If it is 0, it defaults to 500ms.
+	Latency time.Duration
+}
+
+type (
+	// chaosState is the state of the chaos mode of a Mocker.
+	chaosState struct {
+		Chaos
+
+		mut  sync.Mutex
+		rand *rand.Rand
+	}
+
+	// chaosAction is the action the chaos mode takes for a request.
+	chaosAction uint8
+)
+
+const (
+	chaosNone chaosAction = iota
+	chaosRateLimit
+	chaosServerError
+	chaosDrop
+	chaosLatency
+)
+
+var chaosServerErrors = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// WithChaos enables the chaos mode using the passed configuration.
+//
+// In chaos mode, requests to mocked endpoints are randomly answered with a
+// 429 Too Many Requests or a 5xx status, or the connection is dropped.
+// In this case, the mock stays queued up and will be invoked on the next
+// request, i.e. when the client retries the request.
+// Additionally, responses may be randomly delayed.
+//
+// This allows running existing tests as resilience tests, without changing
+// the mocks.
+// Since the client must be able to retry failed requests, WithChaos is
+// usually combined with WithAttempts.
+func WithChaos(c Chaos) Option {
+	if c.RateLimitRate+c.ServerErrorRate+c.DropRate+c.LatencyRate > 1 {
+		panic("dismock: the rates of Chaos must not add up to more than 1")
+	}
+
+	return func(m *Mocker) {
+		if c.Seed == 0 {
+			c.Seed = time.Now().UnixNano()
+		}
+
+		if c.Latency == 0 {
+			c.Latency = 500 * time.Millisecond
+		}
+
+		m.chaos = &chaosState{
+			Chaos: c,
+			rand:  rand.New(rand.NewSource(c.Seed)), //nolint:gosec
+		}
+	}
+}
+
+// roll decides what the chaos mode does with the next request.
+// If the returned action is chaosServerError, status is the status code that
+// shall be sent.
+func (s *chaosState) roll() (action chaosAction, status int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	r := s.rand.Float64()
+
+	switch {
+	case r < s.RateLimitRate:
+		return chaosRateLimit, http.StatusTooManyRequests
+	case r < s.RateLimitRate+s.ServerErrorRate:
+		return chaosServerError, chaosServerErrors[s.rand.Intn(len(chaosServerErrors))]
+	case r < s.RateLimitRate+s.ServerErrorRate+s.DropRate:
+		return chaosDrop, 0
+	case r < s.RateLimitRate+s.ServerErrorRate+s.DropRate+s.LatencyRate:
+		return chaosLatency, 0
+	default:
+		return chaosNone, 0
+	}
+}
+
+// serveChaos writes the response for the passed chaosAction to w.
+// It returns true, if the request was answered.
+func (m *Mocker) serveChaos(w http.ResponseWriter, action chaosAction, status int) bool {
+	switch action {
+	case chaosRateLimit, chaosServerError:
+		writeStatus(m.t, w, status)
+		return true
+	case chaosDrop:
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				_ = conn.Close()
+				return true
+			}
+		}
+
+		// fall back to a server error, if we can't drop the connection
+		writeStatus(m.t, w, http.StatusBadGateway)
+		return true
+	default:
+		return false
+	}
+}
+
+// logChaosSeed logs the seed of the chaos mode, if the test failed.
+//
+// It is registered as a cleanup function of its own, so that the seed is
+// also logged, if the Mocker was closed before it was evaluated.
+func (m *Mocker) logChaosSeed() {
+	if m.t.Failed() {
+		m.t.Logf("chaos seed: %d", m.chaos.Seed)
+	}
+}
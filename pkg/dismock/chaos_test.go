@@ -0,0 +1,109 @@
+package dismock
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithChaos(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t, WithAttempts(0), WithChaos(Chaos{
+			Seed:            1,
+			RateLimitRate:   0.2,
+			ServerErrorRate: 0.2,
+			DropRate:        0.2,
+			LatencyRate:     0.2,
+			Latency:         time.Millisecond,
+		}))
+
+		for i := 1; i <= 10; i++ {
+			m.Channel(discord.Channel{ID: discord.ChannelID(i)})
+		}
+
+		for i := 1; i <= 10; i++ {
+			actual, err := s.Channel(discord.ChannelID(i))
+			require.NoError(t, err)
+			assert.Equal(t, discord.ChannelID(i), actual.ID)
+		}
+	})
+
+	t.Run("random seed", func(t *testing.T) {
+		m := New(t, WithChaos(Chaos{}))
+		assert.NotZero(t, m.chaos.Seed)
+	})
+
+	t.Run("seed logged", func(t *testing.T) {
+		tMock := &cleanupT{T: new(testing.T)}
+
+		m := New(tMock, WithChaos(Chaos{Seed: 123}))
+		m.Close() // prevent m.eval from doing anything
+
+		tMock.Fail()
+		tMock.cleanup()
+
+		assert.Equal(t, []string{"chaos seed: 123"}, tMock.logs)
+	})
+
+	t.Run("invalid rates", func(t *testing.T) {
+		assert.Panics(t, func() {
+			WithChaos(Chaos{RateLimitRate: 0.6, ServerErrorRate: 0.6})
+		})
+	})
+}
+
+func TestChaosState_roll(t *testing.T) {
+	c := Chaos{
+		Seed:            123,
+		RateLimitRate:   0.25,
+		ServerErrorRate: 0.25,
+		DropRate:        0.25,
+		LatencyRate:     0.25,
+	}
+
+	m1 := New(t, WithChaos(c))
+	m2 := New(t, WithChaos(c))
+
+	seen := make(map[chaosAction]bool)
+
+	for i := 0; i < 100; i++ {
+		action1, status1 := m1.chaos.roll()
+		action2, status2 := m2.chaos.roll()
+
+		assert.Equal(t, action1, action2)
+		assert.Equal(t, status1, status2)
+
+		seen[action1] = true
+	}
+
+	assert.Len(t, seen, 4)
+	assert.False(t, seen[chaosNone])
+}
+
+// cleanupT is a *testing.T, that records the functions registered using
+// Cleanup and the messages logged using Logf.
+type cleanupT struct {
+	*testing.T
+
+	cleanups []func()
+	logs     []string
+}
+
+func (t *cleanupT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *cleanupT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+// cleanup calls the registered cleanup functions in reverse order.
+func (t *cleanupT) cleanup() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
@@ -1,7 +1,6 @@
 package dismock
 
 import (
-	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
@@ -16,18 +15,33 @@ import (
 	"github.com/diamondburned/arikawa/v3/utils/sendpart"
 	"github.com/gorilla/schema"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 
 	"github.com/mavolin/dismock/v3/internal/check"
 	"github.com/mavolin/dismock/v3/internal/testing"
 )
 
 // Error simulates an error response for the given path using the given method.
+//
+// The request is not checked.
+// To check the request before responding with an error, use Mocker.WithError
+// instead.
 func (m *Mocker) Error(method, path string, e httputil.HTTPError) {
 	m.MockAPI("Error", method, path, func(w http.ResponseWriter, r *http.Request, t testing.TInterface) {
-		w.WriteHeader(e.Status)
-		err := json.NewEncoder(w).Encode(e)
-		require.NoError(t, err)
+		writeError(t, w, e)
+	})
+}
+
+// =============================================================================
+// bot.go
+// =====================================================================================
+
+// BotURL mocks api.Client.BotURL.
+//
+// To return the data of the Mocker's Gateway, use
+// Mocker.BotURL(m.Gateway.BotData()).
+func (m *Mocker) BotURL(d api.BotData) {
+	m.MockAPI("BotURL", http.MethodGet, "gateway/bot", func(w http.ResponseWriter, r *http.Request, t testing.TInterface) {
+		check.WriteJSON(t, w, d)
 	})
 }
 
@@ -38,6 +38,32 @@ func TestMocker_Error(t *testing.T) {
 	assert.Equal(t, sendErr.Message, httpErr.Message)
 }
 
+// =============================================================================
+// bot.go
+// =====================================================================================
+
+func TestMocker_BotURL(t *testing.T) {
+	m, s := NewSession(t)
+
+	expect := api.BotData{
+		URL:    "wss://gateway.discord.gg",
+		Shards: 2,
+		StartLimit: &api.SessionStartLimit{
+			Total:          1000,
+			Remaining:      999,
+			ResetAfter:     discord.Milliseconds(1000),
+			MaxConcurrency: 1,
+		},
+	}
+
+	m.BotURL(expect)
+
+	actual, err := s.BotURL()
+	require.NoError(t, err)
+
+	assert.Equal(t, expect, *actual)
+}
+
 // =============================================================================
 // channel.go
 // =====================================================================================
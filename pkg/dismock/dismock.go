@@ -10,10 +10,135 @@
 // that those requests are made with Mocker.Client, so that the requests are
 // correctly redirected to the mock server.
 //
+// # Authorization
+//
+// By default, the Mocker doesn't check the Authorization header of requests.
+// Use the WithToken option to require a specific token.
+// Requests with a missing or wrong token will then be answered with a 401
+// Unauthorized.
+//
+// # Permissions
+//
+// Using the WithPermissions option, the guilds, roles and channels of the bot
+// can be described.
+// Requests the bot lacks the permissions for will then be answered with a
+// 403 Missing Permissions, like Discord would, e.g. when deleting someone
+// else's message without the MANAGE_MESSAGES permission.
+//
+// # Audit Log Reasons
+//
+// Mocks of endpoints that accept an audit log reason check the
+// X-Audit-Log-Reason header strictly: Like Discord requires, the header
+// must be URL-encoded, and may contain at most 512 characters when decoded.
+//
+// The mocks expect the reason in plain text, and compare it to the decoded
+// header.
+// arikawa however sends the reason passed to it as is, so reasons
+// containing characters other than printable ASCII characters, or a '%',
+// must be URL-encoded using url.PathEscape before passing them to arikawa,
+// e.g.:
+//
+//	m.Kick(123, 456, "äbc")
+//	err := s.Kick(123, 456, api.AuditLogReason(url.PathEscape("äbc")))
+//
 // # Mocking Errors
 //
 // To send a discord error, use the Mocker.Error method with the path of the
 // endpoint that should return an error.
+// If the request should be checked before the error is returned, use
+// Mocker.WithError and add the mock as usual.
+//
+// To simulate transient failures that are retried by the client, use
+// Mocker.WithTransientErrors.
+// Make sure to configure the number of attempts using the WithAttempts
+// option.
+//
+// Responses that break the contract of an endpoint, e.g. invalid JSON or an
+// HTML error page, can be sent using Mocker.WithMalformedResponse.
+//
+// # Injecting Network Faults
+//
+// Failures that can't be expressed using a status code, such as a connection
+// that is reset while the body is sent, can be injected using
+// Mocker.WithFault.
+// Additionally, Mocker.FailTLSHandshakes, Mocker.FailDials and
+// Mocker.CloseIdleConnections inject faults into the connections of the
+// Mocker's Server and Client.
+//
+// # Chaos Mode
+//
+// Using the WithChaos option, a Mocker can be configured to randomly answer
+// requests with rate limits or server errors, drop connections or delay
+// responses.
+// This allows running existing tests as resilience tests.
+// The seed used is logged, if the test fails.
+//
+// # Gateway
+//
+// Using the WithGateway option, a Mocker starts a mock gateway, that performs
+// the Hello, Identify, Ready handshake.
+// Sessions and states created by the Mocker will connect to it, when opened.
+// Events can then be dispatched to them using Gateway.Dispatch, and
+// Gateway.Sync can be used to wait until they were handled.
+// Commands sent by the client, e.g. presence updates, can be expected using
+// the command mocks of the Gateway, such as Gateway.UpdatePresence.
+//
+// The Gateway verifies that the client heartbeats on schedule.
+// Like Discord, it closes the connection with close code 4008, if the client
+// sends more than 120 payloads, including heartbeats, within 60 seconds, or
+// exceeds its session start limit.
+// To simulate a zombied connection, use Gateway.StopHeartbeatAcks.
+//
+// Gateway.Reconnect, Gateway.InvalidateSession and Gateway.Disconnect
+// script the lifecycle events sent by Discord.
+// When the client resumes, the Gateway verifies the session id and sequence
+// it resumes with, and replays the dispatches it missed.
+// Gateway.CloseConnection closes the connection with one of Discord's close
+// codes, after which Gateway.WaitForReconnect and Gateway.AssertNoReconnect
+// check whether the client reconnects or gives up.
+//
+// Like Discord, the Gateway honors the encoding and compress query
+// parameters, so clients connecting with encoding=etf or
+// compress=zlib-stream can be tested as well.
+//
+// Sharded bots can be tested using NewShardManager.
+// The Gateway verifies the shard of every Identify, enforces the
+// max_concurrency of its session start limit, and routes guild dispatches to
+// the responsible shard.
+//
+// If the client identifies with intents, the Gateway only dispatches events
+// the client subscribed to, and strips data requiring privileged intents the
+// client didn't request, such as message content.
+//
+// The guilds of the client can be set using GatewayConfig.Guilds.
+// They are sent as unavailable guilds in the Ready event, followed by a
+// Guild Create event for each guild.
+// Outages can be simulated using Gateway.GuildUnavailable and
+// Gateway.GuildAvailable.
+//
+// If GatewayConfig.EchoREST is set, successful requests to REST mocks are
+// followed by the event Discord would dispatch in response, e.g. a Message
+// Create event after a message was sent.
+//
+// # Voice
+//
+// Using the WithVoice option, a Mocker additionally starts a mock voice
+// server.
+// Update Voice State commands expected using Gateway.UpdateVoiceState are
+// then answered with the Voice State Update and Voice Server Update events
+// pointing the client to it, so that arikawa's voice.Session can join voice
+// channels.
+// arikawa can only be made to trust the voice server by setting
+// VoiceConfig.TrustViaSSLCertFile, which points SSL_CERT_FILE to the voice
+// server's certificate, see VoiceGateway for details.
+// The voice server performs the voice websocket handshake and answers the
+// IP discovery of the client's UDP connection.
+// The RTP packets sent by the client are decrypted and captured, and can be
+// inspected using VoiceGateway.Packets and VoiceGateway.WaitForPackets.
+// Conversely, audio of other users can be sent to the client using
+// VoiceGateway.SendAudio, and users joining and leaving the voice channel
+// can be announced using VoiceGateway.ClientConnect and
+// VoiceGateway.ClientDisconnect.
 //
 // # Important Notes
 //
@@ -27,26 +152,28 @@
 package dismock
 
 import (
-	"context"
 	"crypto/tls"
 	"math"
-	"net"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/utils/handler"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/session/shard"
 	"github.com/diamondburned/arikawa/v3/state"
 	"github.com/diamondburned/arikawa/v3/state/store"
+	"github.com/diamondburned/arikawa/v3/state/store/defaultstore"
 	"github.com/diamondburned/arikawa/v3/utils/httputil"
 	"github.com/diamondburned/arikawa/v3/utils/httputil/httpdriver"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
 
 	"github.com/mavolin/dismock/v3/internal/testing"
 )
@@ -64,6 +191,12 @@ type (
 		// Client is a mocked *http.Client that redirects all requests to the
 		// Server.
 		Client *http.Client
+		// Gateway is the mock gateway of the Mocker.
+		// It is nil, unless the WithGateway option is used.
+		Gateway *Gateway
+		// Voice is the mock voice server of the Mocker.
+		// It is nil, unless the WithVoice option is used.
+		Voice *VoiceGateway
 
 		// handlers is a map containing all handlers.
 		// The outer map is sorted by path, the inner one by method.
@@ -81,8 +214,44 @@ type (
 		// been called.
 		// If so, eval will not fail.
 		closed bool
+
+		// opts are the Options the Mocker was created with.
+		// They are reapplied to clones of the Mocker.
+		opts []Option
+		// attempts is the number of attempts sessions created by the Mocker
+		// make per request.
+		attempts uint
+		// cabinet is the store.Cabinet of the state created by
+		// NewCachedState.
+		// It is nil, if the Mocker wasn't created using NewCachedState.
+		cabinet *store.Cabinet
+		// cached are the functions used to fill the cabinet by the Cache
+		// methods.
+		cached []func(c *store.Cabinet) error
+		// decorators are applied to every Handler created by Mock.
+		decorators []decorator
+		// faults is the state used to inject faults into the connections of
+		// Server and Client.
+		faults *faultState
+		// token is the value of the Authorization header required for API
+		// requests.
+		// If it is empty, no Authorization header is required.
+		token string
+		// chaos is the state of the chaos mode.
+		// It is nil, if the chaos mode is disabled.
+		chaos *chaosState
+		// permissions is the state used to check the permissions of the bot.
+		// It is nil, if permissions aren't checked.
+		permissions *permissionState
 	}
 
+	// Option is used to configure a Mocker during creation.
+	Option func(m *Mocker)
+
+	// decorator takes a Handler and returns the handlers that shall be queued
+	// up in its place.
+	decorator func(h Handler) []Handler
+
 	// Handler is a named handler for mocked endpoints.
 	Handler struct {
 		// Name is the name of the handler.
@@ -95,16 +264,48 @@ type (
 	MockFunc func(w http.ResponseWriter, r *http.Request, t testing.TInterface)
 )
 
+// WithAttempts sets the total number of times the api.Client of a
+// session.Session or state.State created by the Mocker will attempt a
+// request, if the previous attempts failed with a 5xx or 429 status code.
+// Hence, a request is retried at most attempts-1 times.
+//
+// By default, sessions are created with one attempt, i.e. they never retry.
+// If attempts is 0, requests will be retried until they succeed.
+func WithAttempts(attempts uint) Option {
+	return func(m *Mocker) {
+		m.attempts = attempts
+	}
+}
+
 // New creates a new Mocker with a started server listening on
 // Mocker.Server.Listener.Addr().
-func New(t testing.TInterface) *Mocker {
+func New(t testing.TInterface, opts ...Option) *Mocker {
 	m := &Mocker{
 		handlers: make(map[string]map[string][]Handler, 1),
 		mut:      new(sync.Mutex),
 		t:        t,
+		opts:     opts,
+		attempts: 1,
+		faults:   newFaultState(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
 	}
 
 	m.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			chaosAction chaosAction
+			chaosStatus int
+		)
+
+		if m.chaos != nil {
+			chaosAction, chaosStatus = m.chaos.roll()
+			if chaosAction == chaosLatency {
+				time.Sleep(m.chaos.Latency)
+			}
+		}
+
 		m.mut.Lock()
 		defer m.mut.Unlock()
 
@@ -122,7 +323,27 @@ func New(t testing.TInterface) *Mocker {
 			return
 		}
 
-		h[0].ServeHTTP(w, r)
+		// the handler stays queued up, so that it can be invoked once the
+		// client authorizes correctly
+		if !m.authorized(r) {
+			writeError(m.t, w, unauthorizedError)
+			return
+		}
+
+		// the handler stays queued up, so that it can be invoked by a retry
+		if m.serveChaos(w, chaosAction, chaosStatus) {
+			return
+		}
+
+		// the handler is still invoked, so that the request gets checked
+		if err := m.permissionError(r); err != nil {
+			h[0].ServeHTTP(httptest.NewRecorder(), r)
+			writeError(m.t, w, *err)
+		} else if m.Gateway != nil && m.Gateway.config.EchoREST {
+			m.Gateway.serveEcho(h[0], w, r)
+		} else {
+			h[0].ServeHTTP(w, r)
+		}
 
 		if len(h) == 1 { // this is the only handler for this method
 			if len(methHandlers) == 1 { // the current method is the only method for this path
@@ -135,19 +356,32 @@ func New(t testing.TInterface) *Mocker {
 		}
 	}))
 
+	m.faults.hook(m.Server)
 	m.Server.StartTLS()
 
+	if m.Gateway != nil {
+		m.Gateway.start(m)
+	}
+
+	if m.Voice != nil {
+		m.Voice.start(m)
+	}
+
 	m.Client = &http.Client{
 		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, network, _ string) (conn net.Conn, err error) {
-				return net.Dial(network, m.Server.Listener.Addr().String())
-			},
+			DialContext: m.dialContext,
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true, //nolint:gosec
 			},
 		},
 	}
 
+	// cleanup functions are called in reverse order, so the seed is logged
+	// after the Mocker was evaluated
+	if m.chaos != nil {
+		t.Cleanup(m.logChaosSeed)
+	}
+
 	//goland:noinspection ALL
 	t.Cleanup(m.eval)
 
@@ -156,14 +390,13 @@ func New(t testing.TInterface) *Mocker {
 
 // NewSession creates a new Mocker, starts its test server and returns a
 // manipulated session.Session using the test server.
-func NewSession(t testing.TInterface) (*Mocker, *session.Session) {
-	m := New(t)
-
-	gw := gateway.NewCustom("", "")
-	s := session.NewWithGateway(gw, handler.New())
+func NewSession(t testing.TInterface, opts ...Option) (*Mocker, *session.Session) {
+	m := New(t, opts...)
+	s := m.newSession()
 
-	s.Client.Client = m.HTTPClient()
-	s.Client.Retries = 1
+	if m.Gateway != nil {
+		s.AddSyncHandler(m.Gateway.handle)
+	}
 
 	return m, s
 }
@@ -172,9 +405,107 @@ func NewSession(t testing.TInterface) (*Mocker, *session.Session) {
 // manipulated state.State which's Session uses the test server.
 // In order to allow for successful testing, the State's Store, will always
 // return an error, forcing the use of the (mocked) Session.
-func NewState(t testing.TInterface) (*Mocker, *state.State) {
-	m, se := NewSession(t)
-	return m, state.NewFromSession(se, store.NoopCabinet)
+//
+// To test code that relies on the State's cache, use NewCachedState.
+func NewState(t testing.TInterface, opts ...Option) (*Mocker, *state.State) {
+	m := New(t, opts...)
+	s := state.NewFromSession(m.newSession(), store.NoopCabinet)
+
+	// the state calls its handlers from a handler of the session, so this
+	// needs to be added afterwards
+	if m.Gateway != nil {
+		s.Session.AddSyncHandler(m.Gateway.handle)
+	}
+
+	return m, s
+}
+
+// NewCachedState creates a new Mocker, starts its test server and returns a
+// manipulated state.State which's Session uses the test server.
+// Unlike NewState, the State uses arikawa's default in-memory store, which
+// can be prefilled using the Cache methods of the Mocker, e.g.
+// Mocker.CacheGuild.
+//
+// Since requests that weren't mocked fail the test, this can be used to
+// assert that a lookup is served from the cache, without making a request.
+func NewCachedState(t testing.TInterface, opts ...Option) (*Mocker, *state.State) {
+	m := New(t, opts...)
+	m.cabinet = defaultstore.New()
+
+	s := state.NewFromSession(m.newSession(), m.cabinet)
+
+	// the state calls its handlers from a handler of the session, so this
+	// needs to be added afterwards
+	if m.Gateway != nil {
+		s.Session.AddSyncHandler(m.Gateway.handle)
+	}
+
+	return m, s
+}
+
+// NewShardManager creates a new Mocker, starts its test server and returns a
+// shard.Manager, whose shards are manipulated session.Sessions using the test
+// server.
+// The number of shards and the identify concurrency are taken from the
+// Gateway's BotData.
+//
+// NewShardManager requires the WithGateway option.
+func NewShardManager(t testing.TInterface, opts ...Option) (*Mocker, *shard.Manager) {
+	m := New(t, opts...)
+	if m.Gateway == nil {
+		m.t.Fatal("dismock: NewShardManager requires the WithGateway option")
+		return m, nil
+	}
+
+	data := m.Gateway.BotData()
+
+	id := gateway.DefaultIdentifier(m.token)
+	id.Shard = &gateway.Shard{0, data.Shards}
+	// unlike SetBurst, a new limiter allows all identifies of the first
+	// bucket immediately
+	id.IdentifyShortLimit = rate.NewLimiter(rate.Every(identifyBucketInterval), data.StartLimit.MaxConcurrency)
+
+	mgr, err := shard.NewIdentifiedManagerWithURL(data.URL, id,
+		func(mgr *shard.Manager, id *gateway.Identifier) (shard.Shard, error) {
+			s := m.newSessionWithGateway(gateway.NewCustomWithIdentifier(mgr.GatewayURL(), *id, nil))
+			s.AddSyncHandler(m.Gateway.handle)
+
+			return s, nil
+		})
+	if err != nil {
+		m.t.Fatal("dismock: failed to create shard manager:", err)
+	}
+
+	return m, mgr
+}
+
+// newSession creates a new session.Session using the Mocker's server.
+func (m *Mocker) newSession() *session.Session {
+	var gatewayURL string
+	if m.Gateway != nil {
+		gatewayURL = gateway.AddGatewayParams(m.Gateway.URL())
+	}
+
+	return m.newSessionWithGateway(gateway.NewCustom(gatewayURL, m.token))
+}
+
+// newSessionWithGateway creates a new session.Session using the passed
+// gateway and the Mocker's server.
+func (m *Mocker) newSessionWithGateway(gw *gateway.Gateway) *session.Session {
+	s := session.NewWithGateway(gw, handler.New())
+
+	if m.Gateway != nil {
+		// closing fails, if the session was never opened
+		m.t.Cleanup(func() { _ = s.Close() })
+	}
+
+	// only replace the driver, so that the request options of the api.Client,
+	// e.g. the Authorization header, are preserved
+	s.Client.Client.Client = (*httpdriver.DefaultClient)(m.Client)
+	// despite its name, Retries is the total number of attempts
+	s.Client.Retries = m.attempts
+
+	return s
 }
 
 // HTTPClient wraps the http client of the mocker in a *httputil.Client, as
@@ -216,7 +547,36 @@ func (m *Mocker) Mock(name, method, path string, f MockFunc) {
 		}),
 	}
 
-	m.handlers[path][method] = append(m.handlers[path][method], h)
+	hs := []Handler{h}
+
+	// the decorator that was added first is the outermost
+	for i := len(m.decorators) - 1; i >= 0; i-- {
+		var decorated []Handler
+
+		for _, h := range hs {
+			decorated = append(decorated, m.decorators[i](h)...)
+		}
+
+		hs = decorated
+	}
+
+	m.handlers[path][method] = append(m.handlers[path][method], hs...)
+}
+
+// decorate returns a copy of the Mocker that shares its server and handlers,
+// but applies the passed decorator to all handlers created through it, in
+// addition to the decorators of m.
+//
+// Decorators are applied in reverse order, so that the decorator that was
+// added first, is applied last.
+func (m *Mocker) decorate(d decorator) *Mocker {
+	cp := *m
+
+	cp.decorators = make([]decorator, len(m.decorators), len(m.decorators)+1)
+	copy(cp.decorators, m.decorators)
+	cp.decorators = append(cp.decorators, d)
+
+	return &cp
 }
 
 // MockAPI uses the passed MockFunc to as handler for the passed path and
@@ -246,7 +606,7 @@ func (m *Mocker) MockAPI(name, method, path string, f MockFunc) {
 func (m *Mocker) Clone(t testing.TInterface) (clone *Mocker) {
 	m.Close()
 
-	clone = New(t)
+	clone = New(t, m.opts...)
 	clone.handlers = m.deepCopyHandlers()
 
 	return
@@ -259,7 +619,7 @@ func (m *Mocker) Clone(t testing.TInterface) (clone *Mocker) {
 func (m *Mocker) CloneSession(t testing.TInterface) (clone *Mocker, s *session.Session) {
 	m.Close()
 
-	clone, s = NewSession(t)
+	clone, s = NewSession(t, m.opts...)
 	clone.handlers = m.deepCopyHandlers()
 
 	return
@@ -269,11 +629,24 @@ func (m *Mocker) CloneSession(t testing.TInterface) (clone *Mocker, s *session.S
 // new state.State using the new server.
 // Useful for multiple tests with the same API calls.
 //
+// If the Mocker was created using NewCachedState, so is the clone, and its
+// cache is filled with the entries added using the Cache methods of the
+// Mocker.
+//
 // Creating a clone will automatically close the current server.
 func (m *Mocker) CloneState(t testing.TInterface) (clone *Mocker, s *state.State) {
 	m.Close()
 
-	clone, s = NewState(t)
+	if m.cabinet == nil {
+		clone, s = NewState(t, m.opts...)
+	} else {
+		clone, s = NewCachedState(t, m.opts...)
+
+		for _, set := range m.cached {
+			clone.cache(set)
+		}
+	}
+
 	clone.handlers = m.deepCopyHandlers()
 
 	return
@@ -304,6 +677,8 @@ func (m *Mocker) deepCopyHandlers() (cp map[string]map[string][]Handler) {
 // invoked.
 // If not it will call testing.T.Fatal, printing an error message with all
 // uninvoked handlers.
+// If the Mocker has a Gateway, eval also checks if all expected commands were
+// received.
 //
 // If Close was called before eval, e.g. by calling Clone, eval will always
 // pass.
@@ -312,8 +687,22 @@ func (m *Mocker) eval() {
 		return
 	}
 
+	// give clients the chance to send the commands that are still expected
+	if m.Gateway != nil {
+		m.Gateway.awaitCommands()
+	}
+
 	m.Close()
 
+	// Close doesn't wait for handlers that hijacked their connection, so we
+	// need to wait for them to finish
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.Gateway != nil {
+		m.Gateway.eval()
+	}
+
 	if len(m.handlers) > 0 {
 		m.t.Fatal("there are uninvoked handlers:\n\n" + m.genUninvokedMsg())
 	}
@@ -321,9 +710,18 @@ func (m *Mocker) eval() {
 
 // Close shuts down the server and blocks until all current requests are
 // completed.
+// If the Mocker has a Gateway, all connections to it are closed as well.
 func (m *Mocker) Close() {
 	m.closed = true
 	m.Server.Close()
+
+	if m.Voice != nil {
+		m.Voice.close()
+	}
+
+	if m.Gateway != nil {
+		m.Gateway.close()
+	}
 }
 
 // genUninvokedMsg generates an error message stating the unused handlers.
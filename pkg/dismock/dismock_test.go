@@ -247,22 +247,40 @@ func TestMocker_CloneSession(t *testing.T) {
 }
 
 func TestMocker_CloneState(t *testing.T) {
-	m1 := New(new(testing.T))
+	t.Run("state", func(t *testing.T) {
+		m1 := New(new(testing.T))
 
-	m1.handlers["path"] = map[string][]Handler{
-		http.MethodGet: {},
-	}
+		m1.handlers["path"] = map[string][]Handler{
+			http.MethodGet: {},
+		}
 
-	m2, _ := m1.CloneState(t)
+		m2, _ := m1.CloneState(t)
 
-	assert.NotEqual(t, m1.Client, m2.Client, "clients are the same")
-	assert.Equal(t, m1.handlers, m2.handlers)
+		assert.NotEqual(t, m1.Client, m2.Client, "clients are the same")
+		assert.Equal(t, m1.handlers, m2.handlers)
 
-	m1.handlers["path2"] = map[string][]Handler{http.MethodPatch: {}}
+		m1.handlers["path2"] = map[string][]Handler{http.MethodPatch: {}}
 
-	assert.NotEqual(t, m1.handlers, m2.handlers)
+		assert.NotEqual(t, m1.handlers, m2.handlers)
 
-	m2.Close() // prevent m2.eval from failing
+		m2.Close() // prevent m2.eval from failing
+	})
+
+	t.Run("cached state", func(t *testing.T) {
+		m1, _ := NewCachedState(t)
+
+		expect := discord.Guild{ID: 123, Name: "abc"}
+		m1.CacheGuild(expect)
+
+		m2, s := m1.CloneState(t)
+		require.NotNil(t, m2.cabinet)
+		assert.NotSame(t, m1.cabinet, m2.cabinet)
+
+		// no request is mocked, so the guild must come from the cache
+		actual, err := s.Guild(expect.ID)
+		require.NoError(t, err)
+		assert.Equal(t, expect.Name, actual.Name)
+	})
 }
 
 func TestMocker_deepCopyHandlers(t *testing.T) {
@@ -365,3 +383,50 @@ func TestMocker_genUninvokedMsg(t *testing.T) {
 		assert.Equal(t, expect, m.genUninvokedMsg())
 	})
 }
+
+func TestWithAttempts(t *testing.T) {
+	m, s := NewSession(t, WithAttempts(3))
+
+	var requests int
+
+	for i := 0; i < 3; i++ {
+		m.MockAPI("Channel", http.MethodGet, "channels/123",
+			func(w http.ResponseWriter, _ *http.Request, _ dismocktesting.TInterface) {
+				requests++
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+	}
+
+	_, err := s.Channel(123)
+	require.Error(t, err)
+
+	assert.Equal(t, 3, requests)
+}
+
+func TestMocker_decorate(t *testing.T) {
+	m := New(t)
+
+	var order []string
+
+	d := func(name string) decorator {
+		return func(h Handler) []Handler {
+			return []Handler{{
+				Name: h.Name,
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					order = append(order, name)
+					h.ServeHTTP(w, r)
+				}),
+			}}
+		}
+	}
+
+	dm := m.decorate(d("outer")).decorate(d("inner"))
+	dm.Mock("test", http.MethodGet, "path", nil)
+
+	assert.Empty(t, m.decorators)
+
+	_, err := m.Client.Get("https://" + m.Server.Listener.Addr().String() + "/path")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"outer", "inner"}, order)
+}
@@ -0,0 +1,247 @@
+package dismock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WithTransientErrors returns a copy of the Mocker that shares the Mocker's
+// server and handlers.
+// Every mock added through the returned Mocker will first respond with the
+// passed status codes, one per request, before it is invoked regularly.
+// This can be used to simulate transient failures, such as a 502 Bad Gateway
+// or a 503 Service Unavailable, that are retried by the client.
+//
+// All retries must carry exactly the same body as the first request.
+// Otherwise, the test fails.
+//
+// Since every status code is mocked as a separate request, the session must
+// be configured to retry failed requests, by using WithAttempts.
+//
+// Example
+//
+//	m, s := dismock.NewSession(t, dismock.WithAttempts(3))
+//
+//	m.WithTransientErrors(http.StatusBadGateway, http.StatusServiceUnavailable).
+//		SendMessageComplex(data, msg)
+func (m *Mocker) WithTransientErrors(statuses ...int) *Mocker {
+	return m.decorate(func(h Handler) []Handler {
+		// body is the body of the first request, to which all subsequent
+		// bodies are compared
+		var body []byte
+
+		checkBody := func(r *http.Request, try int) {
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(m.t, err)
+
+			if try == 0 {
+				body = b
+				return
+			}
+
+			assert.Equalf(m.t, string(body), string(b),
+				"%s: body of retry %d differs from the body of the first request", h.Name, try)
+		}
+
+		hs := make([]Handler, 0, len(statuses)+1)
+
+		for i, status := range statuses {
+			i, status := i, status
+
+			hs = append(hs, Handler{
+				Name: h.Name + " (" + strconv.Itoa(status) + ")",
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					checkBody(r, i)
+					writeStatus(m.t, w, status)
+				}),
+			})
+		}
+
+		hs = append(hs, Handler{
+			Name: h.Name,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				checkBody(r, len(statuses))
+				r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+				h.ServeHTTP(w, r)
+			}),
+		})
+
+		return hs
+	})
+}
+
+// WithError returns a copy of the Mocker that shares the Mocker's server and
+// handlers.
+// Every mock added through the returned Mocker will check the request as
+// usual, but respond with the passed error instead of its regular response.
+//
+// Example
+//
+//	m.WithError(httputil.HTTPError{
+//		Status:  http.StatusForbidden,
+//		Code:    50013,
+//		Message: "Missing Permissions",
+//	}).SendMessageComplex(data, msg)
+func (m *Mocker) WithError(e httputil.HTTPError) *Mocker {
+	return m.decorate(func(h Handler) []Handler {
+		return []Handler{{
+			Name: h.Name,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// we only need the checks, not the response
+				h.ServeHTTP(httptest.NewRecorder(), r)
+
+				writeError(m.t, w, e)
+			}),
+		}}
+	})
+}
+
+// writeError writes the passed error to w.
+func writeError(t assert.TestingT, w http.ResponseWriter, e httputil.HTTPError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+
+	err := json.NewEncoder(w).Encode(e)
+	assert.NoError(t, err)
+}
+
+// writeStatus writes a response with the passed status code and a JSON
+// error body resembling those sent by Discord.
+//
+// If the status is 429 Too Many Requests, a Retry-After header of 0 is
+// added, so that the client retries immediately.
+func writeStatus(t assert.TestingT, w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	body := httputil.HTTPError{Message: http.StatusText(status)}
+
+	if status == httputil.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "0")
+		body.Message = "You are being rate limited."
+	}
+
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	assert.NoError(t, err)
+}
+
+// MalformedResponse is a response that deliberately breaks the contract of
+// an endpoint.
+// It can be sent instead of the regular response of a mock, by using
+// Mocker.WithMalformedResponse.
+type MalformedResponse uint8
+
+const (
+	// InvalidJSON sends a body that is not valid JSON.
+	InvalidJSON MalformedResponse = iota + 1
+	// MismatchedJSONType sends a JSON value of the wrong type, i.e. an empty
+	// object if the mock responds with an array, and an empty array
+	// otherwise.
+	MismatchedJSONType
+	// MissingContentType sends the regular response of the mock, but without
+	// a Content-Type header.
+	MissingContentType
+	// CloudflareErrorPage sends an HTML error page with a 502 Bad Gateway
+	// status, as sent by Cloudflare if Discord's servers are unreachable.
+	CloudflareErrorPage
+	// EmptyBody sends a 200 OK with an empty body, although it announces a
+	// JSON body.
+	EmptyBody
+)
+
+// cloudflareErrorPage is a shortened version of the error page Cloudflare
+// sends, if Discord's servers are unreachable.
+const cloudflareErrorPage = `<!DOCTYPE html>
+<html lang="en-US">
+<head>
+<title>discord.com | 502: Bad gateway</title>
+<meta charset="UTF-8" />
+</head>
+<body>
+<div id="cf-wrapper">
+<h1>Bad gateway <span>Error code 502</span></h1>
+<p>The web server reported a bad gateway error.</p>
+<p>Cloudflare Ray ID: <strong>0123456789abcdef</strong></p>
+</div>
+</body>
+</html>
+`
+
+// WithMalformedResponse returns a copy of the Mocker that shares the
+// Mocker's server and handlers.
+// Every mock added through the returned Mocker will send the passed
+// MalformedResponse instead of its regular response.
+// The request itself is still checked by the mock.
+func (m *Mocker) WithMalformedResponse(resp MalformedResponse) *Mocker {
+	return m.decorate(func(h Handler) []Handler {
+		return []Handler{{
+			Name: h.Name,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, r)
+
+				writeMalformed(m.t, w, rec, resp)
+			}),
+		}}
+	})
+}
+
+// writeMalformed writes the passed MalformedResponse to w, using the response
+// recorded by rec as base.
+func writeMalformed(
+	t assert.TestingT, w http.ResponseWriter, rec *httptest.ResponseRecorder, resp MalformedResponse,
+) {
+	var body []byte
+
+	switch resp {
+	case InvalidJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		body = []byte(`{"id":"123",`)
+	case MismatchedJSONType:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if bytes.HasPrefix(bytes.TrimSpace(rec.Body.Bytes()), []byte("[")) {
+			body = []byte("{}")
+		} else {
+			body = []byte("[]")
+		}
+	case MissingContentType:
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+
+		// prevent net/http from sniffing the content type
+		w.Header()["Content-Type"] = nil
+		w.WriteHeader(rec.Code)
+
+		body = rec.Body.Bytes()
+	case CloudflareErrorPage:
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Header().Set("Server", "cloudflare")
+		w.WriteHeader(http.StatusBadGateway)
+
+		body = []byte(cloudflareErrorPage)
+	case EmptyBody:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+	default:
+		assert.Fail(t, "unknown MalformedResponse "+strconv.Itoa(int(resp)))
+		return
+	}
+
+	_, err := w.Write(body)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,173 @@
+package dismock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMocker_WithTransientErrors(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t, WithAttempts(3))
+
+		data := api.SendMessageData{Content: "abc"}
+		expect := discord.Message{ID: 123, ChannelID: 456, Author: discord.User{ID: 789}, Content: "abc"}
+
+		m.WithTransientErrors(http.StatusBadGateway, http.StatusServiceUnavailable).
+			SendMessageComplex(data, expect)
+
+		actual, err := s.SendMessageComplex(expect.ChannelID, data)
+		require.NoError(t, err)
+
+		assert.Equal(t, expect, *actual)
+	})
+
+	t.Run("not enough attempts", func(t *testing.T) {
+		tMock := new(testing.T)
+		m, s := NewSession(tMock)
+
+		m.WithTransientErrors(http.StatusBadGateway).Channel(discord.Channel{ID: 123})
+
+		_, err := s.Channel(123)
+		require.IsType(t, new(httputil.HTTPError), err)
+		assert.Equal(t, http.StatusBadGateway, err.(*httputil.HTTPError).Status)
+
+		c := make(chan struct{})
+
+		go func() { // prevent failure caused by t.Fatal's runtime.Goexit
+			defer func() { c <- struct{}{} }()
+
+			//goland:noinspection ALL
+			m.eval()
+		}()
+
+		<-c
+
+		assert.True(t, tMock.Failed())
+	})
+
+	t.Run("different body", func(t *testing.T) {
+		tMock := new(testing.T)
+		m := New(tMock)
+
+		m.WithTransientErrors(http.StatusBadGateway).Mock("test", http.MethodPost, "path", nil)
+
+		url := "https://" + m.Server.Listener.Addr().String() + "/path"
+
+		resp, err := m.Client.Post(url, "text/plain", strings.NewReader("abc"))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+		resp, err = m.Client.Post(url, "text/plain", strings.NewReader("def"))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestMocker_WithMalformedResponse(t *testing.T) {
+	t.Run("json errors", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			resp MalformedResponse
+		}{
+			{name: "invalid json", resp: InvalidJSON},
+			{name: "mismatched json type", resp: MismatchedJSONType},
+			{name: "empty body", resp: EmptyBody},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m, s := NewSession(t)
+
+				m.WithMalformedResponse(c.resp).Channel(discord.Channel{ID: 123})
+
+				_, err := s.Channel(123)
+				assert.IsType(t, httputil.JSONError{}, err)
+			})
+		}
+	})
+
+	t.Run("mismatched json type array", func(t *testing.T) {
+		m, s := NewSession(t)
+
+		m.WithMalformedResponse(MismatchedJSONType).Channels(456, []discord.Channel{{ID: 123}})
+
+		_, err := s.Channels(456)
+		assert.IsType(t, httputil.JSONError{}, err)
+	})
+
+	t.Run("missing content type", func(t *testing.T) {
+		m := New(t)
+
+		m.WithMalformedResponse(MissingContentType).Channel(discord.Channel{ID: 123})
+
+		resp, err := m.Client.Get("https://" + m.Server.Listener.Addr().String() + "/api/v9/channels/123")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotContains(t, resp.Header, "Content-Type")
+	})
+
+	t.Run("cloudflare error page", func(t *testing.T) {
+		m, s := NewSession(t)
+
+		m.WithMalformedResponse(CloudflareErrorPage).Channel(discord.Channel{ID: 123})
+
+		_, err := s.Channel(123)
+		require.IsType(t, new(httputil.HTTPError), err)
+
+		httpErr := err.(*httputil.HTTPError)
+		assert.Equal(t, http.StatusBadGateway, httpErr.Status)
+		assert.Contains(t, string(httpErr.Body), "Cloudflare")
+	})
+}
+
+func TestMocker_WithError(t *testing.T) {
+	sendErr := httputil.HTTPError{
+		Status:  http.StatusForbidden,
+		Code:    50013,
+		Message: "Missing Permissions",
+	}
+
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t)
+
+		data := api.SendMessageData{Content: "abc"}
+
+		m.WithError(sendErr).SendMessageComplex(data, discord.Message{ChannelID: 123})
+
+		_, err := s.SendMessageComplex(123, data)
+		require.IsType(t, new(httputil.HTTPError), err)
+
+		httpErr := err.(*httputil.HTTPError)
+
+		assert.Equal(t, sendErr.Status, httpErr.Status)
+		assert.Equal(t, sendErr.Code, httpErr.Code)
+		assert.Equal(t, sendErr.Message, httpErr.Message)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		tMock := new(testing.T)
+		m, s := NewSession(tMock)
+
+		m.WithError(sendErr).SendMessageComplex(api.SendMessageData{Content: "abc"}, discord.Message{ChannelID: 123})
+
+		_, err := s.SendMessageComplex(123, api.SendMessageData{Content: "def"})
+		require.IsType(t, new(httputil.HTTPError), err)
+
+		assert.True(t, tMock.Failed())
+	})
+}
@@ -0,0 +1,299 @@
+package dismock
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Fault is a network-level fault that can be injected into the response of
+// a mock using Mocker.WithFault.
+type Fault uint8
+
+const (
+	// ResetConnection sends the status line, the headers and the first half
+	// of the response body and then resets the connection.
+	ResetConnection Fault = iota + 1
+	// TruncateBody sends the status line, the headers and the first half of
+	// the response body and then closes the connection gracefully, although
+	// the Content-Length header announced the full body.
+	TruncateBody
+	// DropConnection closes the connection without sending a response.
+	//
+	// Note that http.Transport transparently retries idempotent requests,
+	// e.g. GET requests, if they were sent on a reused connection that was
+	// closed before a response was received.
+	DropConnection
+)
+
+// ErrInjectedFault is the error used to fail TLS handshakes injected using
+// Mocker.FailTLSHandshakes.
+var ErrInjectedFault = errors.New("dismock: injected fault")
+
+type (
+	// faultState is the state used for injecting faults into the connections
+	// of a Mocker.
+	faultState struct {
+		mut sync.Mutex
+
+		// rawConns maps the remote address of all open connections to their
+		// underlying *net.TCPConn.
+		rawConns map[string]*net.TCPConn
+		// connStates contains the http.ConnState of all open connections.
+		connStates map[net.Conn]http.ConnState
+
+		// failHandshakes is the number of TLS handshakes that shall fail.
+		failHandshakes int
+
+		// failDials is the number of dials that shall fail with dialErr.
+		failDials int
+		dialErr   error
+	}
+
+	// faultListener is a net.Listener that keeps track of the raw
+	// connections it accepts.
+	faultListener struct {
+		net.Listener
+		s *faultState
+	}
+
+	// faultConn is a net.Conn that removes itself from its faultState, once
+	// closed.
+	faultConn struct {
+		*net.TCPConn
+		s *faultState
+	}
+)
+
+func newFaultState() *faultState {
+	return &faultState{
+		rawConns:   make(map[string]*net.TCPConn),
+		connStates: make(map[net.Conn]http.ConnState),
+	}
+}
+
+// hook installs the hooks needed to inject faults into the passed unstarted
+// server.
+func (s *faultState) hook(srv *httptest.Server) {
+	srv.Listener = &faultListener{Listener: srv.Listener, s: s}
+
+	srv.TLS = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			s.mut.Lock()
+			defer s.mut.Unlock()
+
+			if s.failHandshakes > 0 {
+				s.failHandshakes--
+				return nil, ErrInjectedFault
+			}
+
+			return nil, nil
+		},
+	}
+
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+
+		switch state {
+		case http.StateHijacked, http.StateClosed:
+			delete(s.connStates, c)
+		default:
+			s.connStates[c] = state
+		}
+	}
+}
+
+// dial checks if the dial shall fail, and if so returns the error it shall
+// fail with.
+func (s *faultState) dial(network, addr string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.failDials <= 0 {
+		return nil
+	}
+
+	s.failDials--
+
+	if s.dialErr != nil {
+		return s.dialErr
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	return &net.OpError{
+		Op:  "dial",
+		Net: network,
+		Err: &net.DNSError{Err: "no such host", Name: host, IsNotFound: true},
+	}
+}
+
+// rawConn returns the *net.TCPConn with the passed remote address.
+func (s *faultState) rawConn(remoteAddr string) *net.TCPConn {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.rawConns[remoteAddr]
+}
+
+func (l *faultListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	tcpConn, ok := c.(*net.TCPConn)
+	if !ok {
+		return c, nil
+	}
+
+	l.s.mut.Lock()
+	l.s.rawConns[c.RemoteAddr().String()] = tcpConn
+	l.s.mut.Unlock()
+
+	return &faultConn{TCPConn: tcpConn, s: l.s}, nil
+}
+
+func (c *faultConn) Close() error {
+	c.s.mut.Lock()
+	delete(c.s.rawConns, c.RemoteAddr().String())
+	c.s.mut.Unlock()
+
+	return c.TCPConn.Close()
+}
+
+// WithFault returns a copy of the Mocker that shares the Mocker's server and
+// handlers.
+// Every mock added through the returned Mocker will inject the passed Fault
+// into its response.
+// The request itself is still checked by the mock.
+func (m *Mocker) WithFault(f Fault) *Mocker {
+	return m.decorate(func(h Handler) []Handler {
+		return []Handler{{
+			Name: h.Name,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, r)
+
+				m.injectFault(w, rec, f)
+			}),
+		}}
+	})
+}
+
+// injectFault writes the response recorded by rec to w, injecting the passed
+// Fault.
+func (m *Mocker) injectFault(w http.ResponseWriter, rec *httptest.ResponseRecorder, f Fault) {
+	hj, ok := w.(http.Hijacker)
+	require.True(m.t, ok, "http.ResponseWriter does not support hijacking")
+
+	conn, rw, err := hj.Hijack()
+	require.NoError(m.t, err)
+
+	if f == DropConnection {
+		_ = conn.Close()
+		return
+	}
+
+	writeHead(rw.Writer, rec)
+
+	body := rec.Body.Bytes()
+	_, _ = rw.Write(body[:len(body)/2])
+	_ = rw.Flush()
+
+	if f == ResetConnection {
+		if raw := m.faults.rawConn(conn.RemoteAddr().String()); raw != nil {
+			// a linger of 0 discards all unsent data and sends a RST,
+			// instead of a FIN
+			_ = raw.SetLinger(0)
+			_ = raw.Close()
+		}
+	}
+
+	_ = conn.Close()
+}
+
+// writeHead writes the status line and headers recorded by rec to w,
+// announcing the full length of the recorded body.
+func writeHead(w *bufio.Writer, rec *httptest.ResponseRecorder) {
+	_, _ = w.WriteString("HTTP/1.1 " + strconv.Itoa(rec.Code) + " " + http.StatusText(rec.Code) + "\r\n")
+
+	h := rec.Header().Clone()
+	h.Set("Content-Length", strconv.Itoa(rec.Body.Len()))
+	_ = h.Write(w)
+
+	_, _ = w.WriteString("\r\n")
+}
+
+// FailTLSHandshakes makes the next n TLS handshakes with the Mocker's Server
+// fail.
+//
+// To ensure that the next request actually performs a handshake, all idle
+// connections of the Mocker's Client are closed.
+func (m *Mocker) FailTLSHandshakes(n int) {
+	m.faults.mut.Lock()
+	m.faults.failHandshakes = n
+	m.faults.mut.Unlock()
+
+	m.Client.CloseIdleConnections()
+}
+
+// FailDials makes the next n dials of the Mocker's Client fail with the
+// passed error.
+// If err is nil, the dials fail with a *net.DNSError, stating that the host
+// could not be found.
+//
+// To ensure that the next request actually dials, all idle connections of the
+// Mocker's Client are closed.
+func (m *Mocker) FailDials(n int, err error) {
+	m.faults.mut.Lock()
+	m.faults.failDials = n
+	m.faults.dialErr = err
+	m.faults.mut.Unlock()
+
+	m.Client.CloseIdleConnections()
+}
+
+// CloseIdleConnections closes all connections to the Mocker's Server that
+// are currently idle, as a server closing its keep-alive connections would.
+func (m *Mocker) CloseIdleConnections() {
+	var idle []net.Conn
+
+	m.faults.mut.Lock()
+
+	for c, state := range m.faults.connStates {
+		if state == http.StateIdle {
+			idle = append(idle, c)
+			delete(m.faults.connStates, c)
+		}
+	}
+
+	m.faults.mut.Unlock()
+
+	// closing a connection acquires the faultState's mutex
+	for _, c := range idle {
+		_ = c.Close()
+	}
+}
+
+// dialContext dials the Mocker's Server, unless a dial fault is injected.
+func (m *Mocker) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := m.faults.dial(network, addr); err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, m.Server.Listener.Addr().String())
+}
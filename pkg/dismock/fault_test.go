@@ -0,0 +1,148 @@
+package dismock
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMocker_WithFault(t *testing.T) {
+	testCases := []struct {
+		name  string
+		fault Fault
+		// bodyErr is the error expected when reading the body, or nil if
+		// the request itself is expected to fail.
+		bodyErr error
+	}{
+		{
+			name:    "reset connection",
+			fault:   ResetConnection,
+			bodyErr: syscall.ECONNRESET,
+		},
+		{
+			name:    "truncate body",
+			fault:   TruncateBody,
+			bodyErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:  "drop connection",
+			fault: DropConnection,
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			m := New(t)
+
+			m.WithFault(c.fault).Channel(discord.Channel{ID: 123, Name: strings.Repeat("abc", 100)})
+
+			url := "https://" + m.Server.Listener.Addr().String() + "/api/v9/channels/123"
+
+			resp, err := m.Client.Get(url)
+			if c.bodyErr == nil {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			_, err = ioutil.ReadAll(resp.Body)
+			assert.True(t, errors.Is(err, c.bodyErr), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestMocker_FailTLSHandshakes(t *testing.T) {
+	m := New(t)
+	m.Mock("test", http.MethodGet, "path", nil)
+
+	m.FailTLSHandshakes(1)
+
+	url := "https://" + m.Server.Listener.Addr().String() + "/path"
+
+	_, err := m.Client.Get(url)
+	require.Error(t, err)
+
+	resp, err := m.Client.Get(url)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+}
+
+func TestMocker_FailDials(t *testing.T) {
+	t.Run("dns error", func(t *testing.T) {
+		m := New(t)
+		m.Mock("test", http.MethodGet, "path", nil)
+
+		m.FailDials(1, nil)
+
+		_, err := m.Client.Get("https://discord.com/path")
+
+		var dnsErr *net.DNSError
+		require.True(t, errors.As(err, &dnsErr), "unexpected error: %v", err)
+		assert.Equal(t, "discord.com", dnsErr.Name)
+		assert.True(t, dnsErr.IsNotFound)
+
+		resp, err := m.Client.Get("https://discord.com/path")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	})
+
+	t.Run("custom error", func(t *testing.T) {
+		m := New(t)
+
+		expect := errors.New("abc")
+		m.FailDials(1, expect)
+
+		_, err := m.Client.Get("https://discord.com/path")
+		assert.True(t, errors.Is(err, expect), "unexpected error: %v", err)
+	})
+}
+
+func TestMocker_CloseIdleConnections(t *testing.T) {
+	m := New(t)
+	m.Mock("test", http.MethodGet, "path", nil)
+	m.Mock("test", http.MethodGet, "path", nil)
+
+	url := "https://" + m.Server.Listener.Addr().String() + "/path"
+
+	resp, err := m.Client.Get(url)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	idle := func() (n int) {
+		m.faults.mut.Lock()
+		defer m.faults.mut.Unlock()
+
+		for _, state := range m.faults.connStates {
+			if state == http.StateIdle {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	require.Eventually(t, func() bool { return idle() == 1 }, time.Second, 10*time.Millisecond)
+
+	m.CloseIdleConnections()
+	assert.Zero(t, idle())
+
+	resp, err = m.Client.Get(url)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+}
@@ -0,0 +1,791 @@
+package dismock
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+	"github.com/gorilla/websocket"
+
+	"github.com/mavolin/dismock/v3/internal/testing"
+)
+
+// GatewayConfig configures the mock gateway of a Mocker.
+type GatewayConfig struct {
+	// User is the user the client is logged in as.
+	// It is sent as part of the Ready event.
+	User discord.User
+	// Timeout is the maximum duration the Gateway waits for the client, e.g.
+	// when calling Gateway.Sync.
+	// If it is 0, it defaults to 5 seconds.
+	Timeout time.Duration
+	// HeartbeatInterval is the heartbeat interval sent in the Hello event.
+	// If the client doesn't send a heartbeat within 1.5 times the interval,
+	// but at least a second more than the interval, or sends a heartbeat
+	// with a wrong sequence, the test fails.
+	//
+	// Since heartbeats count towards the send rate limit of 120 payloads
+	// per 60 seconds, connections with intervals shorter than 500ms will
+	// eventually be closed for exceeding it.
+	//
+	// If it is 0, it defaults to 41.25 seconds, which is the interval used
+	// by Discord.
+	HeartbeatInterval time.Duration
+	// RequireResumeURL specifies whether clients must use the
+	// resume_gateway_url sent in the Ready event to resume.
+	// If true, the test fails if a client resumes using the regular url.
+	RequireResumeURL bool
+	// Shards is the number of shards clients must use.
+	// If the client identifies with another number of shards, or without
+	// sharding information at all, the test fails.
+	//
+	// If it is 0, the sharding information of the client isn't verified.
+	Shards int
+	// SessionStartLimit is the session start limit returned by
+	// Gateway.BotData.
+	// Identifies exceeding the MaxConcurrency of the limit, make the test
+	// fail and are answered with an Invalid Session event.
+	// Every identify uses up one of the Remaining session starts, and
+	// identifying without any remaining makes the test fail and closes the
+	// connection with close code 4008.
+	// After ResetAfter, Remaining is reset to Total.
+	//
+	// Total defaults to 1000, Remaining to Total, ResetAfter to 24 hours,
+	// and MaxConcurrency to 1.
+	SessionStartLimit api.SessionStartLimit
+	// Guilds are the guilds the client is in.
+	// After the client identified, they are sent as unavailable guilds in
+	// the Ready event, followed by a Guild Create event for each guild, just
+	// like Discord does.
+	// If the client is sharded, only the guilds of the client's shard are
+	// sent.
+	//
+	// Their Members and Presences are also used to answer Request Guild
+	// Members commands expected using Gateway.RequestGuildMembers.
+	//
+	// Guild.Unavailable is ignored.
+	Guilds []gateway.GuildCreateEvent
+	// DropUnsubscribedEvents specifies whether dispatches of events the
+	// client didn't subscribe to using its intents are silently dropped, as
+	// Discord does.
+	// If false, dispatching such an event makes the test fail.
+	//
+	// Regardless of this setting, data only sent with privileged intents,
+	// such as the content of messages, is stripped from events, if the
+	// client didn't request the intent.
+	DropUnsubscribedEvents bool
+	// CompressedMessageSize is the maximum size of a websocket message
+	// containing zlib-stream compressed data.
+	// If a compressed payload is larger, it is split across several
+	// messages.
+	//
+	// If it is 0, every payload is sent in a single message.
+	CompressedMessageSize int
+	// EchoREST specifies whether successful requests to mocked REST
+	// endpoints are followed by the event Discord would dispatch in
+	// response, e.g. a Message Create event after a message was sent, or a
+	// Guild Member Update event after a member was modified.
+	//
+	// The event is created from the response of the mock or, if the mock
+	// has no response body, from the request.
+	// Events of endpoints that only reference a channel, such as Message
+	// Delete or Message Reaction Add, get the guild id of the channel, if
+	// it is one of the Channels or Threads of the Guilds.
+	// Otherwise, they are treated as direct message events.
+	// If no client is connected, no event is dispatched.
+	EchoREST bool
+}
+
+// Gateway is a mock of Discord's gateway.
+//
+// It performs the Hello, Identify, Ready handshake with connecting clients,
+// answers heartbeats, and closes the connection with the appropriate close
+// code, if the client misbehaves.
+//
+// Like Discord, the Gateway honors the encoding and compress query
+// parameters of the url clients connect with, i.e. it supports both json and
+// etf encoding, as well as zlib-stream compression.
+type Gateway struct {
+	// Server is the httptest.Server serving the websocket connections.
+	Server *httptest.Server
+
+	config GatewayConfig
+	t      testing.TInterface
+	// token is the token clients must identify with.
+	// If it is empty, all tokens are accepted.
+	token string
+
+	// wg is used to wait for all connections to be closed.
+	wg sync.WaitGroup
+
+	mut *sync.Mutex
+	// closed indicates whether the Gateway was closed.
+	closed bool
+	// conns are the currently open connections.
+	conns map[*gatewayConn]struct{}
+	// sessions are all sessions created, sorted by id.
+	sessions map[string]*gatewaySession
+	// current is the session that was most recently identified or resumed.
+	// It is nil, if it was invalidated.
+	current *gatewaySession
+	// shards are the current sessions of sharded clients, sorted by shard
+	// id.
+	shards map[int]*gatewaySession
+	// numShards is the number of shards used by sharded clients.
+	numShards int
+	// buckets are the times of the last identify in each identify rate
+	// limit bucket, sorted by rate limit key.
+	buckets map[int]time.Time
+	// connections is the number of connections made.
+	connections int
+	// reconnectBase is the number of connections made, when a connection
+	// was last closed using CloseConnection or Disconnect.
+	reconnectBase int
+	// heartbeats is the number of heartbeats received.
+	heartbeats int
+	// identifies is the number of successful identifies.
+	identifies int
+	// resumes is the number of successful resumes.
+	resumes int
+	// startLimitReset is the time, when the remaining session starts of
+	// the session start limit are reset.
+	startLimitReset time.Time
+
+	// dispatched is the number of dispatches sent.
+	dispatched int
+	// handled is the number of dispatches handled by the sessions created
+	// by the Mocker.
+	handled int
+	// commands are the expected commands, sorted by op code.
+	commands map[ws.OpCode][]gatewayCommand
+	// voice is the voice server of the Mocker.
+	// It is nil, unless the WithVoice option is used.
+	voice *VoiceGateway
+
+	// changed is closed and replaced, every time the state of the Gateway
+	// changes, e.g. when a dispatch was handled.
+	changed chan struct{}
+}
+
+type (
+	// gatewayConn is a connection to the Gateway.
+	gatewayConn struct {
+		g  *Gateway
+		ws *websocket.Conn
+
+		// writeMut is locked while writing to ws.
+		writeMut sync.Mutex
+		// resumeURL indicates whether the connection was made using the
+		// resume url.
+		resumeURL bool
+		// etf indicates whether payloads are encoded using etf instead of
+		// json.
+		etf bool
+		// zlib is the writer used to compress payloads.
+		// It is nil, if the client didn't request compression.
+		zlib *zlib.Writer
+		// zlibBuf is the buffer zlib writes to.
+		zlibBuf bytes.Buffer
+
+		// session is the session the connection identified or resumed
+		// with.
+		// It is nil, if the client hasn't identified yet.
+		session *gatewaySession
+
+		// heartbeatTimer reports a late heartbeat, when it fires.
+		heartbeatTimer *time.Timer
+		// minHeartbeatSeq is the minimum sequence the next heartbeat must
+		// have.
+		minHeartbeatSeq int64
+		// noAcks indicates whether heartbeats are left unacknowledged.
+		noAcks bool
+		// sent are the times the payloads counting towards the send rate
+		// limit were received, sorted from oldest to newest.
+		sent []time.Time
+	}
+
+	// gatewaySession is a session created through an Identify.
+	gatewaySession struct {
+		id string
+		// identify is the Identify command the session was created with.
+		identify gateway.IdentifyCommand
+
+		// mut is locked while sending dispatches, to ensure they are sent
+		// in order.
+		mut sync.Mutex
+
+		// The following fields are guarded by the mutex of the Gateway.
+
+		// seq is the sequence of the last dispatch.
+		seq int64
+		// history contains all dispatches sent, so that they can be
+		// replayed when resuming.
+		history []gatewayPayload
+		// conn is the connection currently using the session.
+		// It is nil, if the client is disconnected.
+		conn *gatewayConn
+		// resumable indicates whether the session can be resumed.
+		resumable bool
+		// minResumeSeq is the minimum sequence a client may resume with.
+		minResumeSeq int64
+	}
+
+	// gatewayPayload is a payload sent over the gateway.
+	gatewayPayload struct {
+		Op   ws.OpCode       `json:"op"`
+		Data json.RawMessage `json:"d"`
+		Seq  int64           `json:"s,omitempty"`
+		Type ws.EventType    `json:"t,omitempty"`
+	}
+)
+
+// Gateway op codes.
+const (
+	dispatchOp       ws.OpCode = 0
+	heartbeatOp      ws.OpCode = 1
+	identifyOp       ws.OpCode = 2
+	resumeOp         ws.OpCode = 6
+	reconnectOp      ws.OpCode = 7
+	invalidSessionOp ws.OpCode = 9
+	helloOp          ws.OpCode = 10
+	heartbeatAckOp   ws.OpCode = 11
+)
+
+const (
+	// defaultHeartbeat is the heartbeat interval used by Discord.
+	defaultHeartbeat = 41250 * time.Millisecond
+	// defaultGatewayTimeout is the default value for GatewayConfig.Timeout.
+	defaultGatewayTimeout = 5 * time.Second
+	// defaultSessionStartLimit is the default value for
+	// GatewayConfig.SessionStartLimit.Total.
+	defaultSessionStartLimit = 1000
+)
+
+// Gateway close codes.
+const (
+	closeUnknownError        = 4000
+	closeUnknownOpcode       = 4001
+	closeDecodeError         = 4002
+	closeNotAuthenticated    = 4003
+	closeAuthenticationError = 4004
+	closeAlreadyAuthed       = 4005
+	closeInvalidSeq          = 4007
+	closeRateLimited         = 4008
+	closeSessionTimedOut     = 4009
+	closeInvalidShard        = 4010
+	closeShardingRequired    = 4011
+	closeInvalidAPIVersion   = 4012
+	closeInvalidIntents      = 4013
+	closeDisallowedIntents   = 4014
+)
+
+var gatewayUpgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// WithGateway starts a mock gateway for the Mocker, that can be accessed
+// through Mocker.Gateway.
+//
+// Sessions and states created by the Mocker will connect to the mock
+// gateway, when opened.
+// They will be closed automatically, when the test finishes.
+func WithGateway(c GatewayConfig) Option {
+	if c.Timeout == 0 {
+		c.Timeout = defaultGatewayTimeout
+	}
+
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = defaultHeartbeat
+	}
+
+	if c.SessionStartLimit.Total == 0 {
+		c.SessionStartLimit.Total = defaultSessionStartLimit
+	}
+
+	if c.SessionStartLimit.Remaining == 0 {
+		c.SessionStartLimit.Remaining = c.SessionStartLimit.Total
+	}
+
+	if c.SessionStartLimit.ResetAfter == 0 {
+		c.SessionStartLimit.ResetAfter = discord.Milliseconds(24 * time.Hour / time.Millisecond)
+	}
+
+	if c.SessionStartLimit.MaxConcurrency == 0 {
+		c.SessionStartLimit.MaxConcurrency = 1
+	}
+
+	return func(m *Mocker) {
+		m.Gateway = &Gateway{
+			config:   c,
+			mut:      new(sync.Mutex),
+			conns:    make(map[*gatewayConn]struct{}),
+			sessions: make(map[string]*gatewaySession),
+			shards:   make(map[int]*gatewaySession),
+			buckets:  make(map[int]time.Time),
+			commands: make(map[ws.OpCode][]gatewayCommand),
+			changed:  make(chan struct{}),
+		}
+	}
+}
+
+// start starts the server of the Gateway.
+func (g *Gateway) start(m *Mocker) {
+	g.t = m.t
+	g.token = m.token
+	g.startLimitReset = time.Now().Add(g.config.SessionStartLimit.ResetAfter.Duration())
+
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+}
+
+// URL returns the url of the Gateway, without any query parameters.
+func (g *Gateway) URL() string {
+	return "ws" + strings.TrimPrefix(g.Server.URL, "http")
+}
+
+// close closes all connections to the Gateway and shuts down its server.
+func (g *Gateway) close() {
+	// after Close returns, all handlers have either returned or hijacked
+	// their connection
+	g.Server.Close()
+
+	g.mut.Lock()
+	g.closed = true
+	conns := make([]*gatewayConn, 0, len(g.conns))
+	for c := range g.conns {
+		conns = append(conns, c)
+	}
+	g.mut.Unlock()
+
+	// close the connections gracefully, so that all payloads sent by the
+	// clients are still read
+	for _, c := range conns {
+		c.writeMut.Lock()
+		_ = c.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.writeMut.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(g.config.Timeout):
+		for _, c := range conns {
+			_ = c.ws.Close()
+		}
+
+		<-done
+	}
+}
+
+// serve upgrades the passed request to a websocket connection, and handles
+// the connection until it is closed.
+func (g *Gateway) serve(w http.ResponseWriter, r *http.Request) {
+	g.wg.Add(1)
+	defer g.wg.Done()
+
+	c := &gatewayConn{g: g, resumeURL: r.URL.Path == resumePath}
+	if err := c.parseParams(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wsConn, err := gatewayUpgrader.Upgrade(w, r, nil)
+	if err != nil { // Upgrade already responded with an error
+		return
+	}
+
+	c.ws = wsConn
+
+	g.mut.Lock()
+	if g.closed {
+		g.mut.Unlock()
+		_ = wsConn.Close()
+		return
+	}
+	g.conns[c] = struct{}{}
+	g.connections++
+	g.notify()
+	g.mut.Unlock()
+
+	c.startHeartbeatTimer()
+
+	defer func() {
+		c.heartbeatTimer.Stop()
+
+		g.mut.Lock()
+		delete(g.conns, c)
+		c.detach()
+		g.mut.Unlock()
+
+		_ = wsConn.Close()
+	}()
+
+	err = c.write(helloOp, gateway.HelloEvent{
+		HeartbeatInterval: discord.Milliseconds(g.config.HeartbeatInterval / time.Millisecond),
+	})
+	if err != nil {
+		return
+	}
+
+	for {
+		_, data, err := wsConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		data, err = c.decode(data)
+		if err != nil {
+			c.close(closeDecodeError)
+			return
+		}
+
+		var p gatewayPayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			c.close(closeDecodeError)
+			return
+		}
+
+		if c.rateLimited() {
+			return
+		}
+
+		if !c.handle(p) {
+			return
+		}
+	}
+}
+
+// handle handles the passed payload sent by the client.
+// It returns false, if the connection was closed.
+func (c *gatewayConn) handle(p gatewayPayload) bool {
+	switch p.Op {
+	case heartbeatOp:
+		return c.heartbeat(p.Data)
+	case identifyOp:
+		if c.authenticated() {
+			c.close(closeAlreadyAuthed)
+			return false
+		}
+
+		var identify gateway.IdentifyCommand
+		if err := json.Unmarshal(p.Data, &identify); err != nil {
+			c.close(closeDecodeError)
+			return false
+		}
+
+		return c.identify(identify)
+	case resumeOp:
+		if c.authenticated() {
+			c.close(closeAlreadyAuthed)
+			return false
+		}
+
+		var resume gateway.ResumeCommand
+		if err := json.Unmarshal(p.Data, &resume); err != nil {
+			c.close(closeDecodeError)
+			return false
+		}
+
+		return c.resume(resume)
+	case updatePresenceOp, updateVoiceStateOp, requestGuildMembersOp:
+		if !c.authenticated() {
+			c.close(closeNotAuthenticated)
+			return false
+		}
+
+		c.command(p.Op, p.Data)
+		return true
+	default:
+		if !c.authenticated() {
+			c.close(closeNotAuthenticated)
+			return false
+		}
+
+		c.close(closeUnknownOpcode)
+		return false
+	}
+}
+
+// authenticated returns whether the connection belongs to a session.
+func (c *gatewayConn) authenticated() bool {
+	c.g.mut.Lock()
+	defer c.g.mut.Unlock()
+
+	return c.session != nil
+}
+
+// identify creates a new session using the passed Identify command and sends
+// the Ready event.
+// It returns false, if the connection was closed.
+func (c *gatewayConn) identify(identify gateway.IdentifyCommand) bool {
+	if !c.g.validToken(identify.Token) {
+		c.close(closeAuthenticationError)
+		return false
+	}
+
+	if !c.checkShard(identify.Shard) {
+		return false
+	}
+
+	g := c.g
+
+	if !g.identifyAllowed(identify.Shard) {
+		return c.write(invalidSessionOp, false) == nil
+	}
+
+	if !c.startSession() {
+		return false
+	}
+
+	g.mut.Lock()
+
+	s := &gatewaySession{
+		id:        fmt.Sprintf("%032x", len(g.sessions)+1),
+		identify:  identify,
+		conn:      c,
+		resumable: true,
+	}
+
+	g.sessions[s.id] = s
+	g.current = s
+	c.session = s
+
+	if identify.Shard != nil && identify.Shard.NumShards() > 1 {
+		g.shards[identify.Shard.ShardID()] = s
+		g.numShards = identify.Shard.NumShards()
+	}
+
+	g.identifies++
+	g.notify()
+
+	g.mut.Unlock()
+
+	version, _ := strconv.Atoi(api.Version)
+
+	guilds := g.shardGuilds(identify.Shard)
+
+	err := g.dispatch(s, &readyEvent{
+		ReadyEvent: &gateway.ReadyEvent{
+			Version:   version,
+			User:      g.config.User,
+			SessionID: s.id,
+			Shard:     identify.Shard,
+		},
+		Guilds:           unavailableGuilds(guilds),
+		ResumeGatewayURL: g.ResumeURL(),
+	})
+	if err != nil {
+		return false
+	}
+
+	return g.sendGuilds(s, guilds) == nil
+}
+
+// Dispatch sends the passed events as dispatches to the session that was
+// most recently identified or resumed.
+// If the client is currently disconnected, the events will be sent when it
+// resumes.
+//
+// If clients are sharded, events are routed to the shard responsible for the
+// guild of the event, as determined by (guild_id >> 22) % num_shards.
+// Events not belonging to a guild, e.g. direct messages, are sent to shard
+// 0.
+//
+// Dispatch doesn't wait for the events to be handled, use Sync for that.
+func (g *Gateway) Dispatch(events ...gateway.Event) {
+	for _, e := range events {
+		if e.Op() != dispatchOp {
+			g.t.Errorf("dismock: %T is not a dispatch event", e)
+			continue
+		}
+
+		s := g.route(e)
+		if s == nil {
+			continue
+		}
+
+		if err := g.dispatch(s, e); err != nil {
+			g.t.Errorf("dismock: failed to dispatch %s: %s", e.EventType(), err)
+		}
+	}
+}
+
+// Sync blocks until the sessions and states created by the Mocker have
+// handled all dispatches sent so far.
+// If that doesn't happen within the Timeout of the Gateway's config, e.g.
+// because an event couldn't be decoded, the test fails.
+//
+// Note that only handlers for specific event types added through
+// AddSyncHandler are waited for.
+// Handlers added through AddHandler are called in a separate goroutine, and
+// synchronous handlers for all events, i.e. handlers taking an interface,
+// such as func(gateway.Event), may still be running when Sync returns.
+func (g *Gateway) Sync() {
+	done := g.waitFor(func() bool { return g.handled >= g.dispatched })
+	if !done {
+		g.mut.Lock()
+		defer g.mut.Unlock()
+
+		g.t.Errorf("dismock: timed out waiting for dispatches to be handled: %d of %d dispatches handled",
+			g.handled, g.dispatched)
+	}
+}
+
+// waitFor waits until cond returns true, or the Timeout of the Gateway
+// passes.
+// cond is called with mut locked, whenever the state of the Gateway changes.
+func (g *Gateway) waitFor(cond func() bool) bool {
+	return g.waitForTimeout(g.config.Timeout, cond)
+}
+
+// waitForTimeout waits until cond returns true, or the passed timeout
+// passes.
+// cond is called with mut locked, whenever the state of the Gateway changes.
+func (g *Gateway) waitForTimeout(timeout time.Duration, cond func() bool) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		g.mut.Lock()
+		done, changed := cond(), g.changed
+		g.mut.Unlock()
+
+		if done {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// notify notifies all waiting calls to waitFor, that the state of the
+// Gateway changed.
+// mut must be locked.
+func (g *Gateway) notify() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// handle is a sync handler added to the sessions created by the Mocker, that
+// keeps track of the number of dispatches handled.
+//
+// Since arikawa calls synchronous handlers for specific events before
+// handlers for all events, all synchronous handlers for the specific event
+// were called, once handle is called.
+// This isn't true for other handlers for all events, which are called in
+// the order they were added, i.e. after handle, if they were added to a
+// session created by the Mocker.
+func (g *Gateway) handle(e interface{}) {
+	if e, ok := e.(gateway.Event); !ok || e.Op() != dispatchOp {
+		return
+	}
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	g.handled++
+	g.notify()
+}
+
+// validToken checks if the passed token is accepted by the Gateway.
+func (g *Gateway) validToken(token string) bool {
+	if g.token == "" {
+		return true
+	}
+
+	// Discord accepts bot tokens with and without prefix
+	return token == g.token || string(BotToken)+" "+token == g.token
+}
+
+// dispatch sends the passed event as dispatch using the next sequence
+// number of the passed session.
+// If the session has no connection, the dispatch will only be added to the
+// session's history.
+func (g *Gateway) dispatch(s *gatewaySession, e gateway.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		g.t.Errorf("dismock: failed to encode gateway payload: %s", err)
+		return err
+	}
+
+	data, ok := g.applyIntents(s, e.EventType(), data)
+	if !ok {
+		return nil
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	g.mut.Lock()
+
+	s.seq++
+	p := gatewayPayload{Op: dispatchOp, Data: data, Seq: s.seq, Type: e.EventType()}
+	s.history = append(s.history, p)
+	c := s.conn
+
+	g.dispatched++
+
+	g.mut.Unlock()
+
+	if c == nil {
+		return nil
+	}
+
+	return c.writePayload(p)
+}
+
+// write sends a payload with the passed op code and data to the client.
+func (c *gatewayConn) write(op ws.OpCode, data interface{}) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		c.g.t.Errorf("dismock: failed to encode gateway payload: %s", err)
+		return err
+	}
+
+	return c.writePayload(gatewayPayload{Op: op, Data: rawData})
+}
+
+// writePayload sends the passed payload to the client.
+func (c *gatewayConn) writePayload(p gatewayPayload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		c.g.t.Errorf("dismock: failed to encode gateway payload: %s", err)
+		return err
+	}
+
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+
+	return c.writeMessage(payload)
+}
+
+// close closes the connection using the passed close code, and the reason
+// Discord sends with it.
+func (c *gatewayConn) close(code int) {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+
+	_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, closeReasons[code]),
+		time.Now().Add(time.Second))
+	_ = c.ws.Close()
+}
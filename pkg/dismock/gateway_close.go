@@ -0,0 +1,83 @@
+package dismock
+
+import "time"
+
+// closeReasons are the reasons Discord sends with its close codes.
+var closeReasons = map[int]string{
+	closeUnknownError:        "Unknown error.",
+	closeUnknownOpcode:       "Unknown opcode.",
+	closeDecodeError:         "Decode error.",
+	closeNotAuthenticated:    "Not authenticated.",
+	closeAuthenticationError: "Authentication failed.",
+	closeAlreadyAuthed:       "Already authenticated.",
+	closeInvalidSeq:          "Invalid seq.",
+	closeRateLimited:         "Rate limited.",
+	closeSessionTimedOut:     "Session timed out.",
+	closeInvalidShard:        "Invalid shard.",
+	closeShardingRequired:    "Sharding required.",
+	closeInvalidAPIVersion:   "Invalid API version.",
+	closeInvalidIntents:      "Invalid intent(s).",
+	closeDisallowedIntents:   "Disallowed intent(s).",
+}
+
+// CloseConnection closes the connection of the session that was most
+// recently identified or resumed with the passed close code, sending the
+// same reason Discord uses for the code.
+//
+// Like Discord, the Gateway invalidates the session, if the close code is
+// 4004 Authentication Failed, 4007 Invalid Seq, 4009 Session Timed Out, or
+// one of the close codes 4010 through 4014, after which clients are not
+// supposed to reconnect.
+// All other close codes, e.g. 4000 Unknown Error or 4008 Rate Limited,
+// allow the client to resume.
+//
+// Use WaitForReconnect or AssertNoReconnect, to check whether the client
+// reconnects.
+func (g *Gateway) CloseConnection(code int) {
+	c := g.currentConn()
+	if c == nil {
+		return
+	}
+
+	g.mut.Lock()
+
+	if s := c.session; s != nil && invalidatesSession(code) {
+		g.invalidate(s)
+	}
+
+	c.detach()
+	g.reconnectBase = g.connections
+
+	g.mut.Unlock()
+
+	c.close(code)
+}
+
+// invalidatesSession returns whether the passed close code invalidates the
+// session.
+func invalidatesSession(code int) bool {
+	switch code {
+	case closeAuthenticationError, closeInvalidSeq, closeSessionTimedOut:
+		return true
+	default:
+		return code >= closeInvalidShard && code <= closeDisallowedIntents
+	}
+}
+
+// WaitForReconnect waits until a client connects to the Gateway, after the
+// last connection closed using CloseConnection or Disconnect was closed.
+// If that doesn't happen within the Timeout of the Gateway, the test fails.
+func (g *Gateway) WaitForReconnect() {
+	if !g.waitFor(func() bool { return g.connections > g.reconnectBase }) {
+		g.t.Error("dismock: timed out waiting for the client to reconnect")
+	}
+}
+
+// AssertNoReconnect waits for the passed duration, and fails the test if a
+// client connected to the Gateway after the last connection closed using
+// CloseConnection or Disconnect was closed.
+func (g *Gateway) AssertNoReconnect(d time.Duration) {
+	if g.waitForTimeout(d, func() bool { return g.connections > g.reconnectBase }) {
+		g.t.Error("dismock: client reconnected, but was expected to give up")
+	}
+}
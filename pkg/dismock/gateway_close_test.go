@@ -0,0 +1,129 @@
+package dismock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_CloseConnection(t *testing.T) {
+	t.Run("close frame", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}))
+
+		c := dialGateway(t, m)
+		defer c.Close()
+
+		c.identify(t)
+
+		m.Gateway.CloseConnection(closeRateLimited)
+
+		_, _, err := c.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+		assert.Equal(t, closeRateLimited, closeErr.Code)
+		assert.Equal(t, "Rate limited.", closeErr.Text)
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			code int
+		}{
+			{name: "authentication failed", code: closeAuthenticationError},
+			{name: "invalid shard", code: closeInvalidShard},
+			{name: "invalid intents", code: closeInvalidIntents},
+			{name: "disallowed intents", code: closeDisallowedIntents},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m, s := NewSession(t, WithGateway(GatewayConfig{}))
+				openSession(t, s)
+
+				m.Gateway.CloseConnection(c.code)
+				m.Gateway.AssertNoReconnect(time.Second)
+			})
+		}
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("arikawa waits 5 seconds before reconnecting")
+		}
+
+		testCases := []struct {
+			name string
+			code int
+			// resume indicates whether the client is expected to resume.
+			resume bool
+		}{
+			{name: "rate limited", code: closeRateLimited, resume: true},
+			{name: "session timed out", code: closeSessionTimedOut, resume: false},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				t.Parallel()
+
+				m, s := NewSession(t, WithGateway(GatewayConfig{Timeout: 15 * time.Second}))
+				openSession(t, s)
+
+				m.Gateway.CloseConnection(c.code)
+				m.Gateway.WaitForReconnect()
+
+				if c.resume {
+					m.Gateway.WaitForResumes(1)
+				} else {
+					m.Gateway.WaitForIdentifies(2)
+				}
+			})
+		}
+	})
+}
+
+func TestGateway_WaitForReconnect(t *testing.T) {
+	tMock := new(testing.T)
+
+	m := New(tMock, WithGateway(GatewayConfig{Timeout: 100 * time.Millisecond}))
+	defer m.Close()
+
+	c := dialGateway(t, m)
+	defer c.Close()
+
+	c.identify(t)
+
+	m.Gateway.CloseConnection(closeUnknownError)
+	m.Gateway.WaitForReconnect()
+
+	assert.True(t, tMock.Failed())
+}
+
+func TestGateway_AssertNoReconnect(t *testing.T) {
+	tMock := new(testing.T)
+
+	m := New(tMock, WithGateway(GatewayConfig{}))
+	defer m.Close()
+
+	c := dialGateway(t, m)
+	defer c.Close()
+
+	c.identify(t)
+
+	m.Gateway.CloseConnection(closeInvalidIntents)
+
+	c = dialGateway(t, m)
+	defer c.Close()
+
+	m.Gateway.AssertNoReconnect(time.Second)
+
+	assert.True(t, tMock.Failed())
+}
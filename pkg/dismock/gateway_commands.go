@@ -0,0 +1,227 @@
+package dismock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+
+	"github.com/mavolin/dismock/v3/internal/check"
+	"github.com/mavolin/dismock/v3/internal/testing"
+)
+
+// Gateway op codes of commands sent by the client.
+const (
+	updatePresenceOp      ws.OpCode = 3
+	updateVoiceStateOp    ws.OpCode = 4
+	requestGuildMembersOp ws.OpCode = 8
+)
+
+type (
+	// gatewayCommand is a named handler for commands sent to the Gateway.
+	gatewayCommand struct {
+		// name is the name of the command.
+		name string
+		// f is the function called with the command's data.
+		f func(c *gatewayConn, data json.RawMessage)
+	}
+
+	// CommandFunc is the function used to check a command sent to the
+	// Gateway.
+	CommandFunc func(data json.RawMessage, t testing.TInterface)
+)
+
+// MockCommand adds an expectation for a command with the passed op code.
+// When the client sends a command with this op code, the passed CommandFunc
+// is called with the command's data.
+//
+// If there are already expectations for the same op code, the expectation
+// will be queued up behind them.
+// Queued up expectations must be met in the same order they were added in.
+// Commands that weren't expected, or expectations that weren't met, will
+// make the test fail.
+//
+// Names don't need to be unique, and have the sole purpose of aiding in
+// debugging.
+//
+// The CommandFunc may be nil, if the data of the command shall not be
+// checked.
+func (g *Gateway) MockCommand(name string, op ws.OpCode, f CommandFunc) {
+	g.addCommand(name, op, func(_ *gatewayConn, data json.RawMessage) {
+		if f != nil {
+			f(data, g.t)
+		}
+	})
+}
+
+// addCommand adds a gatewayCommand with the passed name and function.
+func (g *Gateway) addCommand(name string, op ws.OpCode, f func(c *gatewayConn, data json.RawMessage)) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	g.commands[op] = append(g.commands[op], gatewayCommand{name: name, f: f})
+}
+
+// UpdatePresence adds an expectation for an Update Presence command.
+func (g *Gateway) UpdatePresence(cmd gateway.UpdatePresenceCommand) {
+	g.MockCommand("UpdatePresence", updatePresenceOp, func(data json.RawMessage, t testing.TInterface) {
+		checkCommand(t, &cmd, data)
+	})
+}
+
+// UpdateVoiceState adds an expectation for an Update Voice State command.
+//
+// If the Mocker has a voice server, the Gateway answers the command like
+// Discord, i.e. with a Voice State Update event and, if the client joined a
+// channel, a Voice Server Update event pointing the client to the voice
+// server.
+func (g *Gateway) UpdateVoiceState(cmd gateway.UpdateVoiceStateCommand) {
+	g.addCommand("UpdateVoiceState", updateVoiceStateOp, func(c *gatewayConn, data json.RawMessage) {
+		checkCommand(g.t, &cmd, data)
+
+		if g.voice == nil {
+			return
+		}
+
+		var actual gateway.UpdateVoiceStateCommand
+		if err := json.Unmarshal(data, &actual); err != nil {
+			g.t.Errorf("dismock: failed to decode Update Voice State command: %s", err)
+			return
+		}
+
+		c.joinVoice(actual)
+	})
+}
+
+// RequestGuildMembers adds an expectation for a Request Guild Members
+// command.
+//
+// Like Discord, the Gateway answers the command with Guild Members Chunk
+// events, containing the requested members of the guilds in the
+// GatewayConfig.
+// The members are split into chunks of 1000 members, and users requested by
+// id that aren't members are reported in the not_found field of the first
+// chunk.
+// If the guild isn't in the GatewayConfig, a single empty chunk is sent.
+func (g *Gateway) RequestGuildMembers(cmd gateway.RequestGuildMembersCommand) {
+	g.addCommand("RequestGuildMembers", requestGuildMembersOp, func(c *gatewayConn, data json.RawMessage) {
+		checkCommand(g.t, &cmd, data)
+
+		var actual gateway.RequestGuildMembersCommand
+		if err := json.Unmarshal(data, &actual); err != nil {
+			g.t.Errorf("dismock: failed to decode Request Guild Members command: %s", err)
+			return
+		}
+
+		c.sendMemberChunks(actual)
+	})
+}
+
+// checkCommand checks if the passed data matches the expected command.
+func checkCommand(t testing.TInterface, expect interface{}, data json.RawMessage) {
+	check.JSON(t, expect, io.NopCloser(bytes.NewReader(data)))
+}
+
+// command invokes the first expectation for the passed op code.
+func (c *gatewayConn) command(op ws.OpCode, data json.RawMessage) {
+	g := c.g
+
+	g.mut.Lock()
+
+	cmds := g.commands[op]
+	if len(cmds) == 0 {
+		g.mut.Unlock()
+		g.t.Errorf("dismock: unexpected gateway command with op %d: %s", op, data)
+		return
+	}
+
+	if len(cmds) == 1 {
+		delete(g.commands, op)
+	} else {
+		g.commands[op] = cmds[1:]
+	}
+
+	g.mut.Unlock()
+
+	cmds[0].f(c, data)
+
+	g.mut.Lock()
+	g.notify()
+	g.mut.Unlock()
+}
+
+// awaitCommands waits until all expected commands were received, or the
+// Timeout of the Gateway passes.
+func (g *Gateway) awaitCommands() {
+	g.waitFor(func() bool { return len(g.commands) == 0 })
+}
+
+// eval checks if all expected commands were received.
+// If not, it will call testing.T.Error, printing an error message with all
+// commands that weren't received.
+func (g *Gateway) eval() {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if len(g.commands) > 0 {
+		g.t.Error("there are uninvoked gateway commands:\n\n" + g.genUninvokedMsg())
+	}
+}
+
+// genUninvokedMsg generates an error message stating the commands that
+// weren't received.
+//
+// Example
+//
+//	op 3:
+//		UpdatePresence: 2 uninvoked commands
+func (g *Gateway) genUninvokedMsg() string {
+	ops := make([]int, 0, len(g.commands))
+	for op := range g.commands {
+		ops = append(ops, int(op))
+	}
+
+	sort.Ints(ops)
+
+	var b strings.Builder
+
+	for i, op := range ops {
+		if i > 0 {
+			b.WriteRune('\n')
+		}
+
+		b.WriteString("op ")
+		b.WriteString(strconv.Itoa(op))
+		b.WriteRune(':')
+
+		missing := make(map[string]int)
+		var names []string
+
+		for _, cmd := range g.commands[ws.OpCode(op)] {
+			if missing[cmd.name] == 0 {
+				names = append(names, cmd.name)
+			}
+
+			missing[cmd.name]++
+		}
+
+		for _, name := range names {
+			b.WriteString("\n\t")
+			b.WriteString(name)
+			b.WriteString(": ")
+			b.WriteString(strconv.Itoa(missing[name]))
+			b.WriteString(" uninvoked command")
+
+			if missing[name] > 1 {
+				b.WriteRune('s')
+			}
+		}
+	}
+
+	return b.String()
+}
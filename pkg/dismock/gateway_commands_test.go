@@ -0,0 +1,135 @@
+package dismock
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dismocktesting "github.com/mavolin/dismock/v3/internal/testing"
+)
+
+func TestGateway_MockCommand(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{}))
+		openSession(t, s)
+
+		var actual gateway.UpdateVoiceStateCommand
+
+		m.Gateway.MockCommand("test", updateVoiceStateOp, func(data json.RawMessage, t dismocktesting.TInterface) {
+			require.NoError(t, json.Unmarshal(data, &actual))
+		})
+
+		expect := gateway.UpdateVoiceStateCommand{GuildID: 123, ChannelID: 456}
+		require.NoError(t, s.Gateway().Send(context.Background(), &expect))
+
+		m.Gateway.awaitCommands()
+		assert.Equal(t, expect, actual)
+	})
+
+	t.Run("unexpected", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}))
+		s := m.newSession()
+		openSession(t, s)
+
+		require.NoError(t, s.Gateway().Send(context.Background(), &gateway.UpdatePresenceCommand{}))
+		require.NoError(t, s.Close())
+
+		m.eval()
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestGateway_UpdatePresence(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{}))
+		openSession(t, s)
+
+		cmd := gateway.UpdatePresenceCommand{
+			Activities: []discord.Activity{{Name: "abc", Type: discord.GameActivity}},
+			Status:     discord.DoNotDisturbStatus,
+		}
+		m.Gateway.UpdatePresence(cmd)
+
+		require.NoError(t, s.Gateway().Send(context.Background(), &cmd))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}))
+		s := m.newSession()
+		openSession(t, s)
+
+		m.Gateway.UpdatePresence(gateway.UpdatePresenceCommand{Status: discord.DoNotDisturbStatus})
+
+		err := s.Gateway().Send(context.Background(), &gateway.UpdatePresenceCommand{Status: discord.IdleStatus})
+		require.NoError(t, err)
+
+		m.Gateway.awaitCommands()
+		require.NoError(t, s.Close())
+
+		m.eval()
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestGateway_UpdateVoiceState(t *testing.T) {
+	m, s := NewSession(t, WithGateway(GatewayConfig{}))
+	openSession(t, s)
+
+	cmd := gateway.UpdateVoiceStateCommand{GuildID: 123, ChannelID: 456, SelfDeaf: true}
+	m.Gateway.UpdateVoiceState(cmd)
+
+	require.NoError(t, s.Gateway().Send(context.Background(), &cmd))
+}
+
+func TestGateway_RequestGuildMembers(t *testing.T) {
+	m, s := NewSession(t, WithGateway(GatewayConfig{}))
+	openSession(t, s)
+
+	cmd := gateway.RequestGuildMembersCommand{GuildIDs: []discord.GuildID{123}, Limit: 10, Nonce: "abc"}
+	m.Gateway.RequestGuildMembers(cmd)
+
+	require.NoError(t, s.Gateway().Send(context.Background(), &cmd))
+}
+
+func TestGateway_eval(t *testing.T) {
+	tMock := new(testing.T)
+
+	m := New(tMock, WithGateway(GatewayConfig{Timeout: 1}))
+	m.Gateway.UpdateVoiceState(gateway.UpdateVoiceStateCommand{})
+
+	m.eval()
+	assert.True(t, tMock.Failed())
+}
+
+func TestGateway_genUninvokedMsg(t *testing.T) {
+	t.Run("singular", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}))
+		defer m.Close()
+
+		m.Gateway.MockCommand("command0", 3, nil)
+
+		assert.Equal(t, "op 3:\n\tcommand0: 1 uninvoked command", m.Gateway.genUninvokedMsg())
+	})
+
+	t.Run("plural", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}))
+		defer m.Close()
+
+		m.Gateway.MockCommand("command0", 3, nil)
+		m.Gateway.MockCommand("command0", 3, nil)
+		m.Gateway.MockCommand("command1", ws.OpCode(4), nil)
+
+		expect := "op 3:\n\tcommand0: 2 uninvoked commands\nop 4:\n\tcommand1: 1 uninvoked command"
+		assert.Equal(t, expect, m.Gateway.genUninvokedMsg())
+	})
+}
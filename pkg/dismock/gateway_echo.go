@@ -0,0 +1,355 @@
+package dismock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+)
+
+type (
+	// echoRule maps the endpoints matching method and path to the event
+	// Discord dispatches after a successful request.
+	echoRule struct {
+		// route is the route of the endpoints.
+		route apiRoute
+		// eventType is the type of the event dispatched.
+		eventType ws.EventType
+		// eventFunc creates the data of the event from the request and
+		// response body.
+		// If it returns nil, no event is dispatched.
+		eventFunc func(g *Gateway, path []string, req, resp []byte) interface{}
+	}
+
+	// rawEvent is an event, whose data is already encoded.
+	rawEvent struct {
+		eventType ws.EventType
+		data      json.RawMessage
+	}
+
+	// echoResponseWriter is a http.ResponseWriter recording the status and
+	// body of the response.
+	echoResponseWriter struct {
+		http.ResponseWriter
+		status int
+		body   bytes.Buffer
+		// hijacked specifies whether the connection was hijacked, and the
+		// response was therefore written by other means.
+		hijacked bool
+	}
+)
+
+// echoRules are the echoRules of all endpoints, that cause Discord to
+// dispatch an event.
+var echoRules = []echoRule{
+	// channel endpoints
+	{route: newAPIRoute(http.MethodPatch, "channels/*"), eventType: "CHANNEL_UPDATE", eventFunc: echoResponse},
+	{route: newAPIRoute(http.MethodDelete, "channels/*"), eventType: "CHANNEL_DELETE", eventFunc: echoResponse},
+	{
+		route:     newAPIRoute(http.MethodPost, "channels/*/messages"),
+		eventType: "MESSAGE_CREATE",
+		eventFunc: echoMessage,
+	},
+	{
+		route:     newAPIRoute(http.MethodPatch, "channels/*/messages/*"),
+		eventType: "MESSAGE_UPDATE",
+		eventFunc: echoMessage,
+	},
+	{
+		route:     newAPIRoute(http.MethodDelete, "channels/*/messages/*"),
+		eventType: "MESSAGE_DELETE",
+		eventFunc: echoMessageDelete,
+	},
+	{
+		route:     newAPIRoute(http.MethodPost, "channels/*/messages/bulk-delete"),
+		eventType: "MESSAGE_DELETE_BULK",
+		eventFunc: echoMessageDeleteBulk,
+	},
+	{
+		route:     newAPIRoute(http.MethodPut, "channels/*/messages/*/reactions/*/@me"),
+		eventType: "MESSAGE_REACTION_ADD",
+		eventFunc: echoReaction,
+	},
+	{
+		route:     newAPIRoute(http.MethodDelete, "channels/*/messages/*/reactions/*/@me"),
+		eventType: "MESSAGE_REACTION_REMOVE",
+		eventFunc: echoReaction,
+	},
+	{route: newAPIRoute(http.MethodPut, "channels/*/pins/*"), eventType: "CHANNEL_PINS_UPDATE", eventFunc: echoPins},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/pins/*"), eventType: "CHANNEL_PINS_UPDATE", eventFunc: echoPins},
+	// guild endpoints
+	{route: newAPIRoute(http.MethodPatch, "guilds/*"), eventType: "GUILD_UPDATE", eventFunc: echoResponse},
+	{route: newAPIRoute(http.MethodPost, "guilds/*/channels"), eventType: "CHANNEL_CREATE", eventFunc: echoResponse},
+	{
+		route:     newAPIRoute(http.MethodPatch, "guilds/*/members/*"),
+		eventType: "GUILD_MEMBER_UPDATE",
+		eventFunc: echoMemberUpdate,
+	},
+	{
+		route:     newAPIRoute(http.MethodDelete, "guilds/*/members/*"),
+		eventType: "GUILD_MEMBER_REMOVE",
+		eventFunc: echoGuildUser,
+	},
+	{route: newAPIRoute(http.MethodPut, "guilds/*/bans/*"), eventType: "GUILD_BAN_ADD", eventFunc: echoGuildUser},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/bans/*"), eventType: "GUILD_BAN_REMOVE", eventFunc: echoGuildUser},
+	{route: newAPIRoute(http.MethodPost, "guilds/*/roles"), eventType: "GUILD_ROLE_CREATE", eventFunc: echoRole},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/roles/*"), eventType: "GUILD_ROLE_UPDATE", eventFunc: echoRole},
+	{
+		route:     newAPIRoute(http.MethodDelete, "guilds/*/roles/*"),
+		eventType: "GUILD_ROLE_DELETE",
+		eventFunc: echoRoleDelete,
+	},
+}
+
+// echoResponse uses the response body as event.
+func echoResponse(_ *Gateway, _ []string, _, resp []byte) interface{} {
+	if len(resp) == 0 {
+		return nil
+	}
+
+	return json.RawMessage(resp)
+}
+
+// echoMessage creates a MESSAGE_CREATE or MESSAGE_UPDATE event from the
+// message in the response body.
+// Since messages returned by the REST API don't include a guild id, it is
+// added, if the channel belongs to a guild.
+func echoMessage(g *Gateway, path []string, _, resp []byte) interface{} {
+	if len(resp) == 0 {
+		return nil
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(resp, &msg); err != nil {
+		return json.RawMessage(resp)
+	}
+
+	if _, ok := msg["guild_id"]; !ok {
+		g.addGuildID(msg, path[1])
+	}
+
+	return msg
+}
+
+// echoMessageDelete creates a MESSAGE_DELETE event.
+func echoMessageDelete(g *Gateway, path []string, _, _ []byte) interface{} {
+	return g.addGuildID(map[string]interface{}{"channel_id": path[1], "id": path[3]}, path[1])
+}
+
+// echoMessageDeleteBulk creates a MESSAGE_DELETE_BULK event.
+func echoMessageDeleteBulk(g *Gateway, path []string, req, _ []byte) interface{} {
+	var body struct {
+		Messages []discord.MessageID `json:"messages"`
+	}
+
+	if err := json.Unmarshal(req, &body); err != nil {
+		return nil
+	}
+
+	return g.addGuildID(map[string]interface{}{"channel_id": path[1], "ids": body.Messages}, path[1])
+}
+
+// echoReaction creates a MESSAGE_REACTION_ADD or MESSAGE_REACTION_REMOVE
+// event for the bot's own reaction.
+func echoReaction(g *Gateway, path []string, _, _ []byte) interface{} {
+	apiEmoji, err := url.PathUnescape(path[5])
+	if err != nil {
+		return nil
+	}
+
+	emoji := map[string]string{"name": apiEmoji}
+
+	// custom emojis are formatted as name:id
+	if i := strings.LastIndex(apiEmoji, ":"); i >= 0 {
+		emoji = map[string]string{"name": apiEmoji[:i], "id": apiEmoji[i+1:]}
+	}
+
+	return g.addGuildID(map[string]interface{}{
+		"user_id":    g.config.User.ID,
+		"channel_id": path[1],
+		"message_id": path[3],
+		"emoji":      emoji,
+	}, path[1])
+}
+
+// echoPins creates a CHANNEL_PINS_UPDATE event.
+func echoPins(g *Gateway, path []string, _, _ []byte) interface{} {
+	return g.addGuildID(map[string]interface{}{"channel_id": path[1]}, path[1])
+}
+
+// echoMemberUpdate creates a GUILD_MEMBER_UPDATE event.
+// If the response contains no member, the event only contains the fields
+// that were modified.
+func echoMemberUpdate(_ *Gateway, path []string, req, resp []byte) interface{} {
+	member := make(map[string]json.RawMessage)
+
+	if len(resp) > 0 {
+		if err := json.Unmarshal(resp, &member); err != nil {
+			return nil
+		}
+	} else if len(req) > 0 {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(req, &fields); err != nil {
+			return nil
+		}
+
+		for _, name := range []string{"nick", "roles", "mute", "deaf", "communication_disabled_until"} {
+			if v, ok := fields[name]; ok {
+				member[name] = v
+			}
+		}
+	}
+
+	member["guild_id"], _ = json.Marshal(path[1])
+
+	if _, ok := member["user"]; !ok {
+		member["user"], _ = json.Marshal(map[string]string{"id": path[3]})
+	}
+
+	return member
+}
+
+// echoGuildUser creates an event containing the guild id and the user
+// referenced in the path.
+func echoGuildUser(_ *Gateway, path []string, _, _ []byte) interface{} {
+	return map[string]interface{}{"guild_id": path[1], "user": map[string]string{"id": path[3]}}
+}
+
+// echoRole creates a GUILD_ROLE_CREATE or GUILD_ROLE_UPDATE event.
+func echoRole(_ *Gateway, path []string, _, resp []byte) interface{} {
+	if len(resp) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{"guild_id": path[1], "role": json.RawMessage(resp)}
+}
+
+// echoRoleDelete creates a GUILD_ROLE_DELETE event.
+func echoRoleDelete(_ *Gateway, path []string, _, _ []byte) interface{} {
+	return map[string]string{"guild_id": path[1], "role_id": path[3]}
+}
+
+// addGuildID sets the guild_id field of the passed event to the id of the
+// guild the channel with the passed id belongs to, and returns the event.
+// The guild is looked up in the guilds of the GatewayConfig, if the channel
+// isn't found there, the event is returned unchanged.
+func (g *Gateway) addGuildID(e map[string]interface{}, channelID string) map[string]interface{} {
+	id, err := discord.ParseSnowflake(channelID)
+	if err != nil {
+		return e
+	}
+
+	for _, guild := range g.config.Guilds {
+		for _, c := range guild.Channels {
+			if c.ID == discord.ChannelID(id) {
+				e["guild_id"] = guild.ID
+				return e
+			}
+		}
+
+		for _, c := range guild.Threads {
+			if c.ID == discord.ChannelID(id) {
+				e["guild_id"] = guild.ID
+				return e
+			}
+		}
+	}
+
+	return e
+}
+
+func (e *rawEvent) Op() ws.OpCode                { return dispatchOp }
+func (e *rawEvent) EventType() ws.EventType      { return e.eventType }
+func (e *rawEvent) MarshalJSON() ([]byte, error) { return e.data, nil }
+
+// serveEcho serves the passed request using h, and dispatches the event
+// Discord would dispatch, if the request was successful.
+func (g *Gateway) serveEcho(h http.Handler, w http.ResponseWriter, r *http.Request) {
+	var req []byte
+	if r.Body != nil {
+		req, _ = ioutil.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(req))
+	}
+
+	ew := &echoResponseWriter{ResponseWriter: w}
+	h.ServeHTTP(ew, r)
+
+	if ew.hijacked {
+		return
+	}
+
+	// if nothing was written, the server responds with 200 OK
+	if ew.status != 0 && (ew.status < 200 || ew.status >= 300) {
+		return
+	}
+
+	segments := apiSegments(r)
+
+	for _, rule := range echoRules {
+		if !rule.route.matches(r.Method, segments) {
+			continue
+		}
+
+		e := rule.eventFunc(g, segments, req, ew.body.Bytes())
+		if e == nil {
+			return
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			g.t.Errorf("dismock: failed to encode %s echo: %s", rule.eventType, err)
+			return
+		}
+
+		g.echo(&rawEvent{eventType: rule.eventType, data: data})
+		return
+	}
+}
+
+// echo dispatches the passed event, if a client is connected.
+func (g *Gateway) echo(e *rawEvent) {
+	g.mut.Lock()
+	connected := g.current != nil || len(g.shards) > 0
+	g.mut.Unlock()
+
+	if connected {
+		g.Dispatch(e)
+	}
+}
+
+func (w *echoResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *echoResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack hijacks the underlying http.ResponseWriter, so that faults can be
+// injected.
+func (w *echoResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	w.hijacked = true
+	return hj.Hijack()
+}
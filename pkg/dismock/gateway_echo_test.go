@@ -0,0 +1,199 @@
+package dismock
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayConfig_EchoREST(t *testing.T) {
+	user := discord.User{ID: 123, Username: "abc", Bot: true}
+
+	t.Run("message create", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{User: user, EchoREST: true}))
+
+		var actual *gateway.MessageCreateEvent
+		s.AddSyncHandler(func(e *gateway.MessageCreateEvent) { actual = e })
+
+		openSession(t, s.Session)
+
+		data := api.SendMessageData{Content: "abc"}
+		expect := discord.Message{ID: 456, ChannelID: 789, Author: user, Content: "abc"}
+
+		m.SendMessageComplex(data, expect)
+
+		_, err := s.SendMessageComplex(expect.ChannelID, data)
+		require.NoError(t, err)
+
+		m.Gateway.Sync()
+
+		require.NotNil(t, actual)
+		assert.Equal(t, expect.ID, actual.ID)
+		assert.Equal(t, expect.ChannelID, actual.ChannelID)
+		assert.Equal(t, expect.Content, actual.Content)
+	})
+
+	t.Run("member update", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{User: user, EchoREST: true}))
+
+		var actual *gateway.GuildMemberUpdateEvent
+		s.AddSyncHandler(func(e *gateway.GuildMemberUpdateEvent) { actual = e })
+
+		openSession(t, s.Session)
+
+		var (
+			guildID discord.GuildID = 1
+			userID  discord.UserID  = 2
+		)
+
+		data := api.ModifyMemberData{Nick: option.NewString("def"), Roles: &[]discord.RoleID{3}}
+
+		m.ModifyMember(guildID, userID, data)
+
+		require.NoError(t, s.ModifyMember(guildID, userID, data))
+
+		m.Gateway.Sync()
+
+		require.NotNil(t, actual)
+		assert.Equal(t, guildID, actual.GuildID)
+		assert.Equal(t, userID, actual.User.ID)
+		assert.Equal(t, "def", actual.Nick)
+		assert.Equal(t, []discord.RoleID{3}, actual.RoleIDs)
+	})
+
+	t.Run("reaction add", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{User: user, EchoREST: true}))
+
+		var actual *gateway.MessageReactionAddEvent
+		s.AddSyncHandler(func(e *gateway.MessageReactionAddEvent) { actual = e })
+
+		openSession(t, s.Session)
+
+		emoji := discord.NewCustomEmoji(4, "abc")
+
+		m.React(5, 6, emoji)
+
+		require.NoError(t, s.React(5, 6, emoji))
+
+		m.Gateway.Sync()
+
+		require.NotNil(t, actual)
+		assert.Equal(t, user.ID, actual.UserID)
+		assert.Equal(t, discord.ChannelID(5), actual.ChannelID)
+		assert.Equal(t, discord.MessageID(6), actual.MessageID)
+		assert.Equal(t, discord.EmojiID(4), actual.Emoji.ID)
+		assert.Equal(t, "abc", actual.Emoji.Name)
+	})
+
+	t.Run("guild channel", func(t *testing.T) {
+		guild := gateway.GuildCreateEvent{
+			Guild:    discord.Guild{ID: 1 << 22},
+			Channels: []discord.Channel{{ID: 5, GuildID: 1 << 22, Type: discord.GuildText}},
+		}
+
+		m, mgr := NewShardManager(t, WithGateway(GatewayConfig{
+			User:              user,
+			Shards:            2,
+			SessionStartLimit: api.SessionStartLimit{MaxConcurrency: 2},
+			Guilds:            []gateway.GuildCreateEvent{guild},
+			EchoREST:          true,
+		}))
+
+		received := make([][]discord.GuildID, mgr.NumShards())
+
+		for i := 0; i < mgr.NumShards(); i++ {
+			i := i
+			s := mgr.Shard(i).(*session.Session)
+
+			s.AddIntents(gateway.IntentGuilds | gateway.IntentGuildMessages | gateway.IntentGuildMessageReactions)
+
+			s.AddSyncHandler(func(e *gateway.MessageCreateEvent) { received[i] = append(received[i], e.GuildID) })
+			s.AddSyncHandler(func(e *gateway.MessageDeleteEvent) { received[i] = append(received[i], e.GuildID) })
+			s.AddSyncHandler(func(e *gateway.MessageReactionAddEvent) {
+				received[i] = append(received[i], e.GuildID)
+			})
+			s.AddSyncHandler(func(e *gateway.ChannelPinsUpdateEvent) { received[i] = append(received[i], e.GuildID) })
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, mgr.Open(ctx))
+		defer mgr.Close()
+
+		m.Gateway.WaitForIdentifies(2)
+
+		s := mgr.Shard(0).(*session.Session)
+		emoji := discord.NewCustomEmoji(4, "abc")
+
+		// messages returned by the REST API don't have a guild id
+		m.SendMessage(discord.Message{ID: 6, ChannelID: 5, Author: user, Content: "abc"})
+		m.DeleteMessage(5, 6, "")
+		m.React(5, 6, emoji)
+		m.PinMessage(5, 6, "")
+
+		_, err := s.SendMessage(5, "abc")
+		require.NoError(t, err)
+		require.NoError(t, s.DeleteMessage(5, 6, ""))
+		require.NoError(t, s.React(5, 6, emoji))
+		require.NoError(t, s.PinMessage(5, 6, ""))
+
+		m.Gateway.Sync()
+
+		assert.Empty(t, received[0])
+		assert.Equal(t, []discord.GuildID{guild.ID, guild.ID, guild.ID, guild.ID}, received[1])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{User: user, EchoREST: true}))
+
+		var called bool
+		s.AddSyncHandler(func(*gateway.MessageDeleteEvent) { called = true })
+
+		openSession(t, s.Session)
+
+		m.WithError(httputil.HTTPError{Status: http.StatusNotFound, Code: 10008}).
+			DeleteMessage(1, 2, "")
+
+		require.Error(t, s.DeleteMessage(1, 2, ""))
+
+		m.Gateway.Sync()
+
+		assert.False(t, called)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{User: user}))
+
+		var called bool
+		s.AddSyncHandler(func(*gateway.MessageDeleteEvent) { called = true })
+
+		openSession(t, s.Session)
+
+		m.DeleteMessage(1, 2, "")
+
+		require.NoError(t, s.DeleteMessage(1, 2, ""))
+
+		m.Gateway.Sync()
+
+		assert.False(t, called)
+	})
+
+	t.Run("not connected", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{User: user, EchoREST: true}))
+
+		m.DeleteMessage(1, 2, "")
+
+		require.NoError(t, s.DeleteMessage(1, 2, ""))
+	})
+}
@@ -0,0 +1,106 @@
+package dismock
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/mavolin/dismock/v3/internal/etf"
+)
+
+// Values of the query parameters of the gateway url.
+const (
+	jsonEncoding = "json"
+	etfEncoding  = "etf"
+
+	zlibStreamCompression = "zlib-stream"
+)
+
+// parseParams applies the encoding and compress query parameters of the
+// passed request to the connection.
+// If they are invalid, the test fails and an error is returned.
+func (c *gatewayConn) parseParams(r *http.Request) error {
+	q := r.URL.Query()
+
+	switch encoding := q.Get("encoding"); encoding {
+	case "", jsonEncoding:
+	case etfEncoding:
+		c.etf = true
+	default:
+		c.g.t.Errorf("dismock: client connected to the gateway with invalid encoding %q", encoding)
+		return fmt.Errorf("invalid encoding %q", encoding)
+	}
+
+	switch compress := q.Get("compress"); compress {
+	case "":
+	case zlibStreamCompression:
+		c.zlib = zlib.NewWriter(&c.zlibBuf)
+	default:
+		c.g.t.Errorf("dismock: client connected to the gateway with invalid compression %q", compress)
+		return fmt.Errorf("invalid compression %q", compress)
+	}
+
+	return nil
+}
+
+// decode decodes the passed message sent by the client to JSON.
+func (c *gatewayConn) decode(data []byte) ([]byte, error) {
+	if !c.etf {
+		return data, nil
+	}
+
+	return etf.ToJSON(data)
+}
+
+// writeMessage encodes and compresses the passed JSON payload, as requested
+// by the client, and sends it.
+// writeMut must be locked.
+func (c *gatewayConn) writeMessage(payload []byte) error {
+	msgType := websocket.TextMessage
+
+	if c.etf {
+		var err error
+		if payload, err = etf.FromJSON(payload); err != nil {
+			c.g.t.Errorf("dismock: failed to encode gateway payload: %s", err)
+			return err
+		}
+
+		msgType = websocket.BinaryMessage
+	}
+
+	if c.zlib == nil {
+		return c.ws.WriteMessage(msgType, payload)
+	}
+
+	c.zlibBuf.Reset()
+
+	if _, err := c.zlib.Write(payload); err != nil {
+		return err
+	}
+
+	// flushing ends the frame with the Z_SYNC_FLUSH suffix 0x0000ffff
+	if err := c.zlib.Flush(); err != nil {
+		return err
+	}
+
+	frame := c.zlibBuf.Bytes()
+
+	size := c.g.config.CompressedMessageSize
+	if size <= 0 {
+		size = len(frame)
+	}
+
+	for r := bytes.NewReader(frame); r.Len() > 0; {
+		msg := make([]byte, size)
+		n, _ := r.Read(msg)
+
+		if err := c.ws.WriteMessage(websocket.BinaryMessage, msg[:n]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package dismock
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mavolin/dismock/v3/internal/etf"
+)
+
+func TestGateway_encoding(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			query       string
+			etf         bool
+			zlib        bool
+			messageSize int
+		}{
+			{name: "json", query: "?encoding=json"},
+			{name: "zlib-stream", query: "?encoding=json&compress=zlib-stream", zlib: true},
+			{name: "etf", query: "?encoding=etf", etf: true},
+			{name: "etf zlib-stream", query: "?encoding=etf&compress=zlib-stream", etf: true, zlib: true},
+			{
+				name:        "split frames",
+				query:       "?encoding=json&compress=zlib-stream",
+				zlib:        true,
+				messageSize: 8,
+			},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m := New(t, WithGateway(GatewayConfig{CompressedMessageSize: c.messageSize}))
+
+				conn := dialGatewayURL(t, m.Gateway.URL()+c.query)
+				defer conn.Close()
+
+				identify := []byte(`{"op":2,"d":{"token":"","properties":{}}}`)
+
+				msgType := websocket.TextMessage
+				if c.etf {
+					var err error
+
+					identify, err = etf.FromJSON(identify)
+					require.NoError(t, err)
+
+					msgType = websocket.BinaryMessage
+				}
+
+				require.NoError(t, conn.WriteMessage(msgType, identify))
+
+				for i := 0; i < 2; i++ { // the second payload uses the same stream
+					if i > 0 {
+						m.Gateway.Dispatch(&gateway.TypingStartEvent{ChannelID: 123})
+					}
+
+					messages := conn.messages
+
+					p := conn.read(t)
+					assert.Equal(t, dispatchOp, p.Op)
+					assert.Equal(t, int64(i+1), p.Seq)
+
+					if c.messageSize > 0 {
+						assert.Greater(t, conn.messages-messages, 1, "frame wasn't split")
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			query string
+		}{
+			{name: "encoding", query: "?encoding=xml"},
+			{name: "compress", query: "?compress=zstd-stream"},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				tMock := new(testing.T)
+
+				m := New(tMock, WithGateway(GatewayConfig{}))
+				defer m.Close()
+
+				_, _, err := websocket.DefaultDialer.Dial(m.Gateway.URL()+c.query, nil)
+				assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+
+				assert.True(t, tMock.Failed())
+			})
+		}
+	})
+
+	t.Run("invalid etf", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}))
+
+		conn := dialGatewayURL(t, m.Gateway.URL()+"?encoding=etf")
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"op":1,"d":null}`)))
+
+		_, _, err := conn.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+		assert.Equal(t, closeDecodeError, closeErr.Code)
+	})
+}
@@ -0,0 +1,87 @@
+package dismock
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// unavailableGuild is the stub of an unavailable guild.
+type unavailableGuild struct {
+	ID          discord.GuildID `json:"id"`
+	Unavailable bool            `json:"unavailable"`
+}
+
+// unavailableGuilds returns the unavailable guild stubs for the passed
+// guilds.
+func unavailableGuilds(guilds []gateway.GuildCreateEvent) []unavailableGuild {
+	stubs := make([]unavailableGuild, len(guilds))
+	for i, g := range guilds {
+		stubs[i] = unavailableGuild{ID: g.ID, Unavailable: true}
+	}
+
+	return stubs
+}
+
+// shardGuilds returns the guilds of the GatewayConfig belonging to the
+// passed shard.
+func (g *Gateway) shardGuilds(shard *gateway.Shard) []gateway.GuildCreateEvent {
+	if shard == nil || shard.NumShards() <= 1 {
+		return g.config.Guilds
+	}
+
+	var guilds []gateway.GuildCreateEvent
+
+	for _, guild := range g.config.Guilds {
+		if int(uint64(guild.ID>>22)%uint64(shard.NumShards())) == shard.ShardID() {
+			guilds = append(guilds, guild)
+		}
+	}
+
+	return guilds
+}
+
+// sendGuilds sends a Guild Create event for each of the passed guilds to
+// the passed session.
+func (g *Gateway) sendGuilds(s *gatewaySession, guilds []gateway.GuildCreateEvent) error {
+	// unlike the stubs in the Ready event, Guild Create events require the
+	// GUILDS intent
+	if s.identify.Intents != nil && !gateway.Intents(*s.identify.Intents).Has(gateway.IntentGuilds) {
+		return nil
+	}
+
+	for _, guild := range guilds {
+		guild := guild
+		guild.Unavailable = false
+
+		if err := g.dispatch(s, &guild); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GuildUnavailable dispatches a Guild Delete event for the guild with the
+// passed id, marking the guild as unavailable, as Discord does during
+// outages.
+func (g *Gateway) GuildUnavailable(guildID discord.GuildID) {
+	g.Dispatch(&gateway.GuildDeleteEvent{ID: guildID, Unavailable: true})
+}
+
+// GuildAvailable dispatches the Guild Create event of the guild with the
+// passed id, as found in the Guilds of the GatewayConfig, marking the guild
+// as available again.
+// If there is no such guild, the test fails.
+func (g *Gateway) GuildAvailable(guildID discord.GuildID) {
+	for _, guild := range g.config.Guilds {
+		if guild.ID == guildID {
+			guild := guild
+			guild.Unavailable = false
+
+			g.Dispatch(&guild)
+			return
+		}
+	}
+
+	g.t.Errorf("dismock: guild %d is not in the guilds of the gateway config", guildID)
+}
@@ -0,0 +1,127 @@
+package dismock
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayConfig_Guilds(t *testing.T) {
+	guilds := []gateway.GuildCreateEvent{
+		{
+			Guild: discord.Guild{
+				ID:    1 << 22,
+				Name:  "abc",
+				Roles: []discord.Role{{ID: 1 << 22, Name: "@everyone"}},
+			},
+			Channels: []discord.Channel{
+				{ID: 2, GuildID: 1 << 22, Name: "general", VideoQualityMode: discord.AutoVideoQuality},
+			},
+			Members: []discord.Member{{User: discord.User{ID: 3}}},
+		},
+		{Guild: discord.Guild{ID: 2 << 22, Name: "def"}},
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{Guilds: guilds}))
+
+		c := dialGateway(t, m)
+		defer c.Close()
+
+		require.NoError(t, c.WriteJSON(map[string]interface{}{
+			"op": identifyOp,
+			"d":  gateway.IdentifyCommand{},
+		}))
+
+		p := c.read(t)
+		require.Equal(t, "READY", string(p.Type))
+
+		var ready struct {
+			Guilds json.RawMessage `json:"guilds"`
+		}
+		require.NoError(t, json.Unmarshal(p.Data, &ready))
+
+		assert.JSONEq(t, `[{"id":"4194304","unavailable":true},{"id":"8388608","unavailable":true}]`,
+			string(ready.Guilds))
+
+		for _, expect := range guilds {
+			p = c.read(t)
+			require.Equal(t, "GUILD_CREATE", string(p.Type))
+
+			var actual gateway.GuildCreateEvent
+			require.NoError(t, json.Unmarshal(p.Data, &actual))
+
+			assert.Equal(t, expect.ID, actual.ID)
+			assert.Equal(t, expect.Roles, actual.Roles)
+			assert.Equal(t, expect.Channels, actual.Channels)
+			assert.Len(t, actual.Members, len(expect.Members))
+		}
+	})
+
+	t.Run("state", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{Guilds: guilds}))
+
+		var ready []discord.GuildID
+		s.AddSyncHandler(func(e *state.GuildReadyEvent) { ready = append(ready, e.ID) })
+
+		openSession(t, s.Session)
+		m.Gateway.Sync()
+
+		assert.Equal(t, []discord.GuildID{1 << 22, 2 << 22}, ready)
+	})
+
+	t.Run("shard", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{Guilds: guilds}))
+
+		actual := m.Gateway.shardGuilds(&gateway.Shard{1, 2})
+		require.Len(t, actual, 1)
+		assert.Equal(t, discord.GuildID(1<<22), actual[0].ID)
+	})
+
+	t.Run("missing intent", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{Guilds: guilds}))
+		s.AddIntents(gateway.IntentGuildMessages)
+
+		var created bool
+		s.AddSyncHandler(func(*gateway.GuildCreateEvent) { created = true })
+
+		openSession(t, s.Session)
+		m.Gateway.Sync()
+
+		assert.False(t, created)
+	})
+}
+
+func TestGateway_GuildUnavailable(t *testing.T) {
+	guild := gateway.GuildCreateEvent{Guild: discord.Guild{ID: 123, Name: "abc"}}
+
+	m, s := NewState(t, WithGateway(GatewayConfig{Guilds: []gateway.GuildCreateEvent{guild}}))
+
+	var unavailable, available []discord.GuildID
+	s.AddSyncHandler(func(e *state.GuildUnavailableEvent) { unavailable = append(unavailable, e.ID) })
+	s.AddSyncHandler(func(e *state.GuildAvailableEvent) { available = append(available, e.ID) })
+
+	openSession(t, s.Session)
+
+	m.Gateway.GuildUnavailable(guild.ID)
+	m.Gateway.GuildAvailable(guild.ID)
+	m.Gateway.Sync()
+
+	assert.Equal(t, []discord.GuildID{guild.ID}, unavailable)
+	assert.Equal(t, []discord.GuildID{guild.ID}, available)
+}
+
+func TestGateway_GuildAvailable(t *testing.T) {
+	tMock := new(testing.T)
+
+	m, s := NewSession(tMock, WithGateway(GatewayConfig{}))
+	openSession(t, s)
+
+	m.Gateway.GuildAvailable(123)
+	assert.True(t, tMock.Failed())
+}
@@ -0,0 +1,115 @@
+package dismock
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// minHeartbeatGrace is the minimum duration a heartbeat may be late.
+const minHeartbeatGrace = time.Second
+
+// heartbeatDeadline returns the duration after which a heartbeat is
+// considered late.
+func (g *Gateway) heartbeatDeadline() time.Duration {
+	grace := g.config.HeartbeatInterval / 2
+	if grace < minHeartbeatGrace {
+		grace = minHeartbeatGrace
+	}
+
+	return g.config.HeartbeatInterval + grace
+}
+
+// startHeartbeatTimer starts the timer that reports a late heartbeat.
+func (c *gatewayConn) startHeartbeatTimer() {
+	deadline := c.g.heartbeatDeadline()
+
+	c.heartbeatTimer = time.AfterFunc(deadline, func() {
+		c.g.t.Errorf("dismock: client didn't send a heartbeat within %s", deadline)
+	})
+}
+
+// heartbeat handles a heartbeat sent by the client.
+// It returns false, if the connection was closed.
+func (c *gatewayConn) heartbeat(data json.RawMessage) bool {
+	var seq *int64
+	if err := json.Unmarshal(data, &seq); err != nil {
+		c.close(closeDecodeError)
+		return false
+	}
+
+	c.heartbeatTimer.Reset(c.g.heartbeatDeadline())
+
+	c.g.mut.Lock()
+
+	var lastSeq int64
+	if c.session != nil {
+		lastSeq = c.session.seq
+	}
+
+	// the client may not have received the dispatches sent since the last
+	// heartbeat yet, but it must have received all dispatches sent before
+	minSeq := c.minHeartbeatSeq
+	c.minHeartbeatSeq = lastSeq
+
+	ack := !c.noAcks
+
+	c.g.heartbeats++
+	c.g.notify()
+
+	c.g.mut.Unlock()
+
+	var actualSeq int64
+	if seq != nil {
+		actualSeq = *seq
+	}
+
+	if actualSeq < minSeq || actualSeq > lastSeq {
+		c.g.t.Errorf("dismock: heartbeat has sequence %d, but the last sequence sent was %d", actualSeq, lastSeq)
+	}
+
+	if !ack {
+		return true
+	}
+
+	return c.write(heartbeatAckOp, nil) == nil
+}
+
+// StopHeartbeatAcks stops acknowledging the heartbeats sent by the client
+// that most recently identified, simulating a zombied connection.
+// Connections established afterwards will receive Heartbeat ACKs as usual.
+func (g *Gateway) StopHeartbeatAcks() {
+	c := g.currentConn()
+	if c == nil {
+		return
+	}
+
+	g.mut.Lock()
+	c.noAcks = true
+	g.mut.Unlock()
+}
+
+// WaitForHeartbeats waits until the Gateway received a total of n
+// heartbeats, or the Timeout of the Gateway passes, in which case the test
+// fails.
+func (g *Gateway) WaitForHeartbeats(n int) {
+	if !g.waitFor(func() bool { return g.heartbeats >= n }) {
+		g.mut.Lock()
+		defer g.mut.Unlock()
+
+		g.t.Errorf("dismock: timed out waiting for heartbeats: received %d of %d heartbeats", g.heartbeats, n)
+	}
+}
+
+// WaitForConnections waits until a total of n connections were made to the
+// Gateway, or the Timeout of the Gateway passes, in which case the test
+// fails.
+//
+// This can be used to assert that the client reconnects.
+func (g *Gateway) WaitForConnections(n int) {
+	if !g.waitFor(func() bool { return g.connections >= n }) {
+		g.mut.Lock()
+		defer g.mut.Unlock()
+
+		g.t.Errorf("dismock: timed out waiting for connections: %d of %d connections made", g.connections, n)
+	}
+}
@@ -0,0 +1,69 @@
+package dismock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_heartbeat(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{HeartbeatInterval: 50 * time.Millisecond}))
+		openSession(t, s)
+
+		m.Gateway.WaitForHeartbeats(2)
+
+		m.Gateway.Dispatch(&gateway.TypingStartEvent{}, &gateway.TypingStartEvent{})
+		m.Gateway.Sync()
+
+		m.Gateway.WaitForHeartbeats(5)
+	})
+
+	t.Run("wrong sequence", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}))
+		defer m.Close()
+
+		c := dialGateway(t, m)
+		defer c.Close()
+
+		require.NoError(t, c.WriteJSON(map[string]interface{}{"op": 1, "d": 5}))
+
+		m.Gateway.WaitForHeartbeats(1)
+		assert.True(t, tMock.Failed())
+	})
+
+	t.Run("late", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{HeartbeatInterval: 10 * time.Millisecond}))
+		defer m.Close()
+
+		c := dialGateway(t, m)
+		defer c.Close()
+
+		time.Sleep(minHeartbeatGrace + 100*time.Millisecond)
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestGateway_StopHeartbeatAcks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("arikawa waits 5 seconds before reconnecting")
+	}
+
+	m, s := NewSession(t, WithGateway(GatewayConfig{
+		Timeout:           10 * time.Second,
+		HeartbeatInterval: 50 * time.Millisecond,
+	}))
+	openSession(t, s)
+
+	m.Gateway.WaitForHeartbeats(1)
+	m.Gateway.StopHeartbeatAcks()
+
+	m.Gateway.WaitForConnections(2)
+}
@@ -0,0 +1,185 @@
+package dismock
+
+import (
+	"encoding/json"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+)
+
+// IntentMessageContent is the privileged intent required to receive the
+// content of messages.
+// It is missing in arikawa.
+const IntentMessageContent gateway.Intents = 1 << 15
+
+// intentNames are the names of the intents, as used in Discord's
+// documentation.
+var intentNames = map[gateway.Intents]string{
+	gateway.IntentGuilds:                 "GUILDS",
+	gateway.IntentGuildMembers:           "GUILD_MEMBERS",
+	gateway.IntentGuildBans:              "GUILD_BANS",
+	gateway.IntentGuildEmojis:            "GUILD_EMOJIS_AND_STICKERS",
+	gateway.IntentGuildIntegrations:      "GUILD_INTEGRATIONS",
+	gateway.IntentGuildWebhooks:          "GUILD_WEBHOOKS",
+	gateway.IntentGuildInvites:           "GUILD_INVITES",
+	gateway.IntentGuildVoiceStates:       "GUILD_VOICE_STATES",
+	gateway.IntentGuildPresences:         "GUILD_PRESENCES",
+	gateway.IntentGuildMessages:          "GUILD_MESSAGES",
+	gateway.IntentGuildMessageReactions:  "GUILD_MESSAGE_REACTIONS",
+	gateway.IntentGuildMessageTyping:     "GUILD_MESSAGE_TYPING",
+	gateway.IntentDirectMessages:         "DIRECT_MESSAGES",
+	gateway.IntentDirectMessageReactions: "DIRECT_MESSAGE_REACTIONS",
+	gateway.IntentDirectMessageTyping:    "DIRECT_MESSAGE_TYPING",
+	IntentMessageContent:                 "MESSAGE_CONTENT",
+	gateway.IntentGuildScheduledEvents:   "GUILD_SCHEDULED_EVENTS",
+}
+
+// Intents returns the intents the session that was most recently identified
+// or resumed identified with.
+// ok is false, if there is no such session, or if the client identified
+// without intents.
+func (g *Gateway) Intents() (intents gateway.Intents, ok bool) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.current == nil || g.current.identify.Intents == nil {
+		return 0, false
+	}
+
+	return gateway.Intents(*g.current.identify.Intents), true
+}
+
+// applyIntents applies the intents of the passed session to the data of an
+// event of the passed type.
+// It strips all data the session didn't request, and returns false, if the
+// session didn't subscribe to the event at all.
+func (g *Gateway) applyIntents(s *gatewaySession, eventType ws.EventType, data []byte) ([]byte, bool) {
+	if s.identify.Intents == nil { // user accounts don't use intents
+		return data, true
+	}
+
+	intents := gateway.Intents(*s.identify.Intents)
+
+	guildID := eventGuildID(eventType, data)
+
+	if required := requiredIntent(eventType, guildID.IsValid()); required != 0 && !intents.Has(required) {
+		if !g.config.DropUnsubscribedEvents {
+			g.t.Errorf("dismock: client didn't subscribe to %s events, because it is missing the %s intent",
+				eventType, intentNames[required])
+		}
+
+		return data, false
+	}
+
+	if eventType != "MESSAGE_CREATE" && eventType != "MESSAGE_UPDATE" && eventType != "GUILD_CREATE" {
+		return data, true
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data, true
+	}
+
+	if eventType == "GUILD_CREATE" {
+		if _, ok := fields["presences"]; ok && !intents.Has(gateway.IntentGuildPresences) {
+			fields["presences"] = json.RawMessage("[]")
+		}
+
+		if !intents.Has(gateway.IntentGuildMembers) {
+			g.stripMembers(fields)
+		}
+	} else if !intents.Has(IntentMessageContent) && guildID.IsValid() && !g.receivesContent(fields) {
+		stripContent(fields)
+	}
+
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return data, true
+	}
+
+	return stripped, true
+}
+
+// requiredIntent returns the intent required to receive events of the passed
+// type.
+// guild indicates whether the event was sent in a guild.
+// If the event doesn't require an intent, 0 is returned.
+func requiredIntent(eventType ws.EventType, guild bool) gateway.Intents {
+	intents := gateway.EventIntents[eventType]
+
+	// Events sent both in guilds and direct messages require one intent for
+	// each.
+	// The intent for guilds always is the lower one.
+	guildIntent := intents & -intents
+	if guild || guildIntent == intents {
+		return guildIntent
+	}
+
+	return intents &^ guildIntent
+}
+
+// receivesContent checks if the bot receives the content of the passed guild
+// message without the MESSAGE_CONTENT intent, because it is the message's
+// author, or was mentioned.
+func (g *Gateway) receivesContent(msg map[string]json.RawMessage) bool {
+	botID := g.config.User.ID
+	if !botID.IsValid() {
+		return false
+	}
+
+	var author discord.User
+	if err := json.Unmarshal(msg["author"], &author); err == nil && author.ID == botID {
+		return true
+	}
+
+	var mentions []discord.User
+	_ = json.Unmarshal(msg["mentions"], &mentions)
+
+	for _, u := range mentions {
+		if u.ID == botID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stripContent strips the fields requiring the MESSAGE_CONTENT intent from
+// the passed message.
+func stripContent(msg map[string]json.RawMessage) {
+	for field, empty := range map[string]string{
+		"content":     `""`,
+		"embeds":      "[]",
+		"attachments": "[]",
+		"components":  "[]",
+	} {
+		// MESSAGE_UPDATE events may be partial
+		if _, ok := msg[field]; ok {
+			msg[field] = json.RawMessage(empty)
+		}
+	}
+}
+
+// stripMembers removes all members except the bot's own member from the
+// passed GUILD_CREATE event.
+func (g *Gateway) stripMembers(guild map[string]json.RawMessage) {
+	var members []json.RawMessage
+	if err := json.Unmarshal(guild["members"], &members); err != nil {
+		return
+	}
+
+	own := make([]json.RawMessage, 0, 1)
+
+	for _, member := range members {
+		var m struct {
+			User discord.User `json:"user"`
+		}
+
+		if err := json.Unmarshal(member, &m); err == nil && m.User.ID.IsValid() && m.User.ID == g.config.User.ID {
+			own = append(own, member)
+		}
+	}
+
+	guild["members"], _ = json.Marshal(own)
+}
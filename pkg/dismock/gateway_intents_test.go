@@ -0,0 +1,209 @@
+package dismock
+
+import (
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_Intents(t *testing.T) {
+	t.Run("intents", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{}))
+		s.AddIntents(gateway.IntentGuildMessages | IntentMessageContent)
+		openSession(t, s)
+
+		actual, ok := m.Gateway.Intents()
+		require.True(t, ok)
+		assert.Equal(t, gateway.IntentGuildMessages|IntentMessageContent, actual)
+	})
+
+	t.Run("no intents", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{}))
+		openSession(t, s)
+
+		_, ok := m.Gateway.Intents()
+		assert.False(t, ok)
+	})
+}
+
+func TestGateway_applyIntents(t *testing.T) {
+	t.Run("unsubscribed", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			event  gateway.Event
+			expect bool
+		}{
+			{
+				name:   "guild",
+				event:  &gateway.TypingStartEvent{GuildID: 123, ChannelID: 456},
+				expect: false,
+			},
+			{
+				name:   "direct message",
+				event:  &gateway.TypingStartEvent{ChannelID: 456},
+				expect: true,
+			},
+			{
+				name:   "no intent required",
+				event:  &gateway.UserUpdateEvent{},
+				expect: true,
+			},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				t.Run("fail", func(t *testing.T) {
+					tMock := new(testing.T)
+
+					m, s := NewSession(tMock, WithGateway(GatewayConfig{}))
+					s.AddIntents(gateway.IntentDirectMessageTyping)
+					openSession(t, s)
+
+					var received bool
+					s.AddSyncHandler(func(*gateway.TypingStartEvent) { received = true })
+					s.AddSyncHandler(func(*gateway.UserUpdateEvent) { received = true })
+
+					m.Gateway.Dispatch(c.event)
+					m.Gateway.Sync()
+
+					assert.Equal(t, c.expect, received)
+					assert.Equal(t, !c.expect, tMock.Failed())
+				})
+
+				t.Run("drop", func(t *testing.T) {
+					m, s := NewSession(t, WithGateway(GatewayConfig{DropUnsubscribedEvents: true}))
+					s.AddIntents(gateway.IntentDirectMessageTyping)
+					openSession(t, s)
+
+					var received bool
+					s.AddSyncHandler(func(*gateway.TypingStartEvent) { received = true })
+					s.AddSyncHandler(func(*gateway.UserUpdateEvent) { received = true })
+
+					m.Gateway.Dispatch(c.event)
+					m.Gateway.Sync()
+
+					assert.Equal(t, c.expect, received)
+				})
+			})
+		}
+	})
+
+	t.Run("message content", func(t *testing.T) {
+		const botID = 123
+
+		testCases := []struct {
+			name    string
+			intents gateway.Intents
+			msg     discord.Message
+			expect  string
+		}{
+			{
+				name:    "stripped",
+				intents: gateway.IntentGuildMessages,
+				msg:     discord.Message{GuildID: 1, Author: discord.User{ID: 2}, Content: "abc"},
+				expect:  "",
+			},
+			{
+				name:    "intent",
+				intents: gateway.IntentGuildMessages | IntentMessageContent,
+				msg:     discord.Message{GuildID: 1, Author: discord.User{ID: 2}, Content: "abc"},
+				expect:  "abc",
+			},
+			{
+				name:    "direct message",
+				intents: gateway.IntentDirectMessages,
+				msg:     discord.Message{Author: discord.User{ID: 2}, Content: "abc"},
+				expect:  "abc",
+			},
+			{
+				name:    "own message",
+				intents: gateway.IntentGuildMessages,
+				msg:     discord.Message{GuildID: 1, Author: discord.User{ID: botID}, Content: "abc"},
+				expect:  "abc",
+			},
+			{
+				name:    "mentioned",
+				intents: gateway.IntentGuildMessages,
+				msg: discord.Message{
+					GuildID:  1,
+					Author:   discord.User{ID: 2},
+					Content:  "abc",
+					Mentions: []discord.GuildUser{{User: discord.User{ID: botID}}},
+				},
+				expect: "abc",
+			},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m, s := NewSession(t, WithGateway(GatewayConfig{User: discord.User{ID: botID}}))
+				s.AddIntents(c.intents)
+				openSession(t, s)
+
+				var actual discord.Message
+				s.AddSyncHandler(func(e *gateway.MessageCreateEvent) { actual = e.Message })
+
+				m.Gateway.Dispatch(&gateway.MessageCreateEvent{Message: c.msg})
+				m.Gateway.Sync()
+
+				assert.Equal(t, c.expect, actual.Content)
+			})
+		}
+	})
+
+	t.Run("guild create", func(t *testing.T) {
+		const botID = 123
+
+		guild := gateway.GuildCreateEvent{
+			Guild: discord.Guild{ID: 1},
+			Members: []discord.Member{
+				{User: discord.User{ID: botID}},
+				{User: discord.User{ID: 2}},
+			},
+			Presences: []discord.Presence{{User: discord.User{ID: 2}}},
+		}
+
+		testCases := []struct {
+			name            string
+			intents         gateway.Intents
+			expectMembers   int
+			expectPresences int
+		}{
+			{name: "stripped", intents: gateway.IntentGuilds, expectMembers: 1, expectPresences: 0},
+			{
+				name:            "intents",
+				intents:         gateway.IntentGuilds | gateway.IntentGuildMembers | gateway.IntentGuildPresences,
+				expectMembers:   2,
+				expectPresences: 1,
+			},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				m, s := NewSession(t, WithGateway(GatewayConfig{User: discord.User{ID: botID}}))
+				s.AddIntents(c.intents)
+				openSession(t, s)
+
+				var actual *gateway.GuildCreateEvent
+				s.AddSyncHandler(func(e *gateway.GuildCreateEvent) { actual = e })
+
+				e := guild
+				m.Gateway.Dispatch(&e)
+				m.Gateway.Sync()
+
+				require.NotNil(t, actual)
+				assert.Len(t, actual.Members, c.expectMembers)
+				assert.Len(t, actual.Presences, c.expectPresences)
+			})
+		}
+	})
+}
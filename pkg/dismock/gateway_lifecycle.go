@@ -0,0 +1,238 @@
+package dismock
+
+import (
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// resumePath is the path of the resume url.
+const resumePath = "/resume"
+
+// readyEvent is a gateway.ReadyEvent, that includes the resume_gateway_url
+// missing in arikawa's struct, and sends guilds as unavailable guild stubs,
+// as Discord does.
+type readyEvent struct {
+	*gateway.ReadyEvent
+	Guilds           []unavailableGuild `json:"guilds"`
+	ResumeGatewayURL string             `json:"resume_gateway_url"`
+}
+
+// ResumeURL returns the url clients should use to resume, without any query
+// parameters.
+// It is sent as resume_gateway_url in the Ready event.
+func (g *Gateway) ResumeURL() string {
+	return g.URL() + resumePath
+}
+
+// resume resumes the session with the passed Resume command and replays all
+// dispatches the client missed.
+// It returns false, if the connection was closed.
+func (c *gatewayConn) resume(resume gateway.ResumeCommand) bool {
+	g := c.g
+
+	if !g.validToken(resume.Token) {
+		c.close(closeAuthenticationError)
+		return false
+	}
+
+	g.mut.Lock()
+	s := g.sessions[resume.SessionID]
+	g.mut.Unlock()
+
+	if s == nil {
+		g.t.Errorf("dismock: client resumed unknown session %q", resume.SessionID)
+		return c.write(invalidSessionOp, false) == nil
+	}
+
+	if g.config.RequireResumeURL && !c.resumeURL {
+		g.t.Error("dismock: client resumed without using the resume_gateway_url")
+	}
+
+	s.mut.Lock()
+
+	g.mut.Lock()
+
+	if !s.resumable {
+		g.mut.Unlock()
+		s.mut.Unlock()
+
+		return c.write(invalidSessionOp, false) == nil
+	}
+
+	if resume.Sequence > s.seq {
+		g.mut.Unlock()
+		s.mut.Unlock()
+
+		g.t.Errorf("dismock: client resumed with sequence %d, but the last sequence sent was %d",
+			resume.Sequence, s.seq)
+		c.close(closeInvalidSeq)
+		return false
+	}
+
+	if resume.Sequence < s.minResumeSeq {
+		g.t.Errorf("dismock: client resumed with sequence %d, but already received sequence %d",
+			resume.Sequence, s.minResumeSeq)
+	}
+
+	if s.conn != nil { // the old connection is still open
+		s.conn.detach()
+	}
+
+	s.conn = c
+	c.session = s
+	g.current = s
+
+	var missed []gatewayPayload
+	for _, p := range s.history {
+		if p.Seq > resume.Sequence {
+			missed = append(missed, p)
+		}
+	}
+
+	g.resumes++
+	g.notify()
+
+	g.mut.Unlock()
+
+	for _, p := range missed {
+		if err := c.writePayload(p); err != nil {
+			s.mut.Unlock()
+			return false
+		}
+	}
+
+	s.mut.Unlock()
+
+	return g.dispatch(s, new(gateway.ResumedEvent)) == nil
+}
+
+// Reconnect sends a Reconnect event to the client of the session that was
+// most recently identified or resumed, instructing it to reconnect and
+// resume.
+func (g *Gateway) Reconnect() {
+	c := g.currentConn()
+	if c == nil {
+		return
+	}
+
+	if err := c.write(reconnectOp, nil); err != nil {
+		g.t.Errorf("dismock: failed to send reconnect: %s", err)
+	}
+}
+
+// InvalidateSession sends an Invalid Session event to the client of the
+// session that was most recently identified or resumed.
+// resumable is sent as the event's data, and indicates whether the client
+// may resume.
+// If resumable is false, the session can no longer be resumed, and the
+// client must identify again.
+func (g *Gateway) InvalidateSession(resumable bool) {
+	c := g.currentConn()
+	if c == nil {
+		return
+	}
+
+	g.mut.Lock()
+
+	if s := c.session; s != nil && !resumable {
+		g.invalidate(s)
+	}
+
+	c.detach()
+
+	g.mut.Unlock()
+
+	if err := c.write(invalidSessionOp, resumable); err != nil {
+		g.t.Errorf("dismock: failed to send invalid session: %s", err)
+	}
+}
+
+// Disconnect abruptly closes the connection of the session that was most
+// recently identified or resumed, without sending a close frame.
+// The session can be resumed.
+//
+// Use WaitForReconnect or AssertNoReconnect, to check whether the client
+// reconnects.
+func (g *Gateway) Disconnect() {
+	c := g.currentConn()
+	if c == nil {
+		return
+	}
+
+	g.mut.Lock()
+	c.detach()
+	g.reconnectBase = g.connections
+	g.mut.Unlock()
+
+	_ = c.ws.Close()
+}
+
+// invalidate invalidates the passed session, so that it can no longer be
+// resumed.
+// The mutex of the Gateway must be locked.
+func (g *Gateway) invalidate(s *gatewaySession) {
+	s.resumable = false
+
+	if g.current == s {
+		g.current = nil
+	}
+
+	if shard := s.identify.Shard; shard != nil && g.shards[shard.ShardID()] == s {
+		delete(g.shards, shard.ShardID())
+	}
+}
+
+// detach detaches the connection from its session, so that the session can
+// be resumed.
+// The mutex of the Gateway must be locked.
+func (c *gatewayConn) detach() {
+	s := c.session
+	if s == nil {
+		return
+	}
+
+	c.session = nil
+
+	if s.conn == c {
+		s.conn = nil
+		s.minResumeSeq = c.minHeartbeatSeq
+	}
+}
+
+// currentConn returns the connection of the session that was most recently
+// identified or resumed.
+// If there is none, the test fails and nil is returned.
+func (g *Gateway) currentConn() *gatewayConn {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.current == nil || g.current.conn == nil {
+		g.t.Error("dismock: no client is connected to the gateway")
+		return nil
+	}
+
+	return g.current.conn
+}
+
+// WaitForIdentifies waits until clients successfully identified a total of n
+// times, or the Timeout of the Gateway passes, in which case the test
+// fails.
+func (g *Gateway) WaitForIdentifies(n int) {
+	if !g.waitFor(func() bool { return g.identifies >= n }) {
+		g.mut.Lock()
+		defer g.mut.Unlock()
+
+		g.t.Errorf("dismock: timed out waiting for identifies: %d of %d identifies received", g.identifies, n)
+	}
+}
+
+// WaitForResumes waits until clients successfully resumed a total of n
+// times, or the Timeout of the Gateway passes, in which case the test
+// fails.
+func (g *Gateway) WaitForResumes(n int) {
+	if !g.waitFor(func() bool { return g.resumes >= n }) {
+		g.mut.Lock()
+		defer g.mut.Unlock()
+
+		g.t.Errorf("dismock: timed out waiting for resumes: %d of %d resumes received", g.resumes, n)
+	}
+}
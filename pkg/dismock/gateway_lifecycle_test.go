@@ -0,0 +1,230 @@
+package dismock
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_resume(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{RequireResumeURL: true}))
+
+		c := dialGateway(t, m)
+		defer c.Close()
+
+		ready := c.identify(t)
+		assert.Equal(t, m.Gateway.ResumeURL(), ready.ResumeGatewayURL)
+
+		m.Gateway.Dispatch(
+			&gateway.TypingStartEvent{ChannelID: 1},
+			&gateway.TypingStartEvent{ChannelID: 2},
+			&gateway.TypingStartEvent{ChannelID: 3},
+		)
+
+		p := c.read(t)
+		assert.Equal(t, int64(2), p.Seq)
+
+		m.Gateway.Disconnect()
+
+		c = dialGatewayURL(t, ready.ResumeGatewayURL)
+		defer c.Close()
+
+		require.NoError(t, c.WriteJSON(gatewayPayload{
+			Op:   resumeOp,
+			Data: []byte(`{"token":"","session_id":"` + ready.SessionID + `","seq":2}`),
+		}))
+
+		m.Gateway.WaitForResumes(1)
+
+		for _, seq := range []int64{3, 4} {
+			p = c.read(t)
+			assert.Equal(t, seq, p.Seq)
+			assert.Equal(t, "TYPING_START", string(p.Type))
+		}
+
+		p = c.read(t)
+		assert.Equal(t, int64(5), p.Seq)
+		assert.Equal(t, "RESUMED", string(p.Type))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			sessionID string
+			seq       int64
+			resumeURL bool
+			// expectOp is the op code of the expected payload.
+			// If it is -1, the connection is expected to be closed.
+			expectOp int
+		}{
+			{name: "unknown session", sessionID: "abc", seq: 1, resumeURL: true, expectOp: int(invalidSessionOp)},
+			{name: "sequence too high", seq: 5, resumeURL: true, expectOp: -1},
+			{name: "sequence too low", seq: 0, resumeURL: true, expectOp: int(dispatchOp)},
+			{name: "no resume url", seq: 1, expectOp: int(dispatchOp)},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				tMock := new(testing.T)
+
+				m := New(tMock, WithGateway(GatewayConfig{RequireResumeURL: true}))
+				defer m.Close()
+
+				conn := dialGateway(t, m)
+				defer conn.Close()
+
+				ready := conn.identify(t)
+
+				// heartbeat, so that the client is known to have received
+				// the ready event
+				require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": heartbeatOp, "d": 1}))
+				m.Gateway.WaitForHeartbeats(1)
+
+				m.Gateway.Disconnect()
+
+				sessionID := ready.SessionID
+				if c.sessionID != "" {
+					sessionID = c.sessionID
+				}
+
+				url := m.Gateway.URL()
+				if c.resumeURL {
+					url = m.Gateway.ResumeURL()
+				}
+
+				conn = dialGatewayURL(t, url)
+				defer conn.Close()
+
+				require.NoError(t, conn.WriteJSON(map[string]interface{}{
+					"op": resumeOp,
+					"d":  map[string]interface{}{"token": "", "session_id": sessionID, "seq": c.seq},
+				}))
+
+				_, data, err := conn.ReadMessage()
+				if c.expectOp == -1 {
+					var closeErr *websocket.CloseError
+					require.ErrorAs(t, err, &closeErr)
+					assert.Equal(t, closeInvalidSeq, closeErr.Code)
+				} else {
+					require.NoError(t, err)
+					assert.Contains(t, string(data), `"op":`+strconv.Itoa(c.expectOp))
+				}
+
+				assert.True(t, tMock.Failed())
+			})
+		}
+	})
+}
+
+func TestGateway_Reconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("arikawa waits 5 seconds before reconnecting")
+	}
+
+	m, s := NewSession(t, WithGateway(GatewayConfig{Timeout: 10 * time.Second}))
+	openSession(t, s)
+
+	m.Gateway.Dispatch(&gateway.TypingStartEvent{ChannelID: 123})
+	m.Gateway.Sync()
+
+	m.Gateway.Reconnect()
+	m.Gateway.WaitForResumes(1)
+
+	typing := make(chan discord.ChannelID, 1)
+	s.AddHandler(func(e *gateway.TypingStartEvent) { typing <- e.ChannelID })
+
+	m.Gateway.Dispatch(&gateway.TypingStartEvent{ChannelID: 456})
+	m.Gateway.Sync()
+
+	assert.Equal(t, discord.ChannelID(456), <-typing)
+}
+
+func TestGateway_InvalidateSession(t *testing.T) {
+	// arikawa identifies again, even if the session is resumable, so resume
+	// manually
+	t.Run("resumable", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}))
+
+		c := dialGateway(t, m)
+		defer c.Close()
+
+		ready := c.identify(t)
+
+		m.Gateway.Dispatch(&gateway.TypingStartEvent{ChannelID: 1})
+
+		p := c.read(t)
+		assert.Equal(t, int64(2), p.Seq)
+
+		m.Gateway.InvalidateSession(true)
+
+		p = c.read(t)
+		assert.Equal(t, invalidSessionOp, p.Op)
+		assert.Equal(t, "true", string(p.Data))
+
+		c = dialGatewayURL(t, ready.ResumeGatewayURL)
+		defer c.Close()
+
+		require.NoError(t, c.WriteJSON(map[string]interface{}{
+			"op": resumeOp,
+			"d":  map[string]interface{}{"token": "", "session_id": ready.SessionID, "seq": 2},
+		}))
+
+		m.Gateway.WaitForResumes(1)
+
+		p = c.read(t)
+		assert.Equal(t, int64(3), p.Seq)
+		assert.Equal(t, "RESUMED", string(p.Type))
+	})
+
+	t.Run("not resumable", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("arikawa waits 5 seconds before reconnecting")
+		}
+
+		m, s := NewSession(t, WithGateway(GatewayConfig{Timeout: 10 * time.Second}))
+		openSession(t, s)
+
+		m.Gateway.Dispatch(&gateway.TypingStartEvent{})
+		m.Gateway.Sync()
+
+		m.Gateway.InvalidateSession(false)
+		m.Gateway.WaitForIdentifies(2)
+		m.Gateway.Sync()
+
+		assert.Equal(t, 0, m.Gateway.resumes)
+	})
+
+	t.Run("not connected", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}))
+		defer m.Close()
+
+		m.Gateway.InvalidateSession(true)
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestGateway_Disconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("arikawa waits 5 seconds before reconnecting")
+	}
+
+	m, s := NewSession(t, WithGateway(GatewayConfig{Timeout: 10 * time.Second}))
+	openSession(t, s)
+
+	m.Gateway.Disconnect()
+	m.Gateway.Dispatch(&gateway.TypingStartEvent{})
+
+	m.Gateway.WaitForResumes(1)
+	m.Gateway.Sync()
+}
@@ -0,0 +1,95 @@
+package dismock
+
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	// sendLimit is the number of payloads a client may send per connection
+	// within sendLimitInterval.
+	sendLimit = 120
+	// sendLimitInterval is the interval sendLimit applies to.
+	sendLimitInterval = time.Minute
+)
+
+// rateLimited checks if the client exceeded the send rate limit by sending
+// another payload.
+// If so, the test fails, the connection is closed and true is returned.
+//
+// Like Discord, all payloads are counted, including heartbeats.
+func (c *gatewayConn) rateLimited() bool {
+	now := time.Now()
+
+	// sent is sorted, so all expired payloads are at the beginning
+	i := 0
+	for i < len(c.sent) && now.Sub(c.sent[i]) >= sendLimitInterval {
+		i++
+	}
+
+	c.sent = c.sent[i:]
+
+	if len(c.sent) >= sendLimit {
+		c.g.t.Errorf("dismock: client sent more than %d payloads within %s, the first %s ago",
+			sendLimit, sendLimitInterval, now.Sub(c.sent[0]))
+		c.close(closeRateLimited)
+		return true
+	}
+
+	c.sent = append(c.sent, now)
+	return false
+}
+
+// startSession uses up one of the remaining session starts of the session
+// start limit.
+// If there are none, the test fails, the connection is closed and false is
+// returned.
+func (c *gatewayConn) startSession() bool {
+	g := c.g
+
+	g.mut.Lock()
+
+	g.resetStartLimit()
+
+	if g.config.SessionStartLimit.Remaining <= 0 {
+		g.t.Errorf("dismock: client exceeded the session start limit of %d identifies with %d identifies",
+			g.config.SessionStartLimit.Total, g.identifies+1)
+		g.mut.Unlock()
+
+		c.close(closeRateLimited)
+		return false
+	}
+
+	g.config.SessionStartLimit.Remaining--
+
+	g.mut.Unlock()
+	return true
+}
+
+// resetStartLimit resets the remaining session starts, if the reset after
+// of the session start limit passed.
+//
+// g.mut must be locked.
+func (g *Gateway) resetStartLimit() {
+	if now := time.Now(); !now.Before(g.startLimitReset) {
+		g.config.SessionStartLimit.Remaining = g.config.SessionStartLimit.Total
+		g.startLimitReset = now.Add(g.config.SessionStartLimit.ResetAfter.Duration())
+	}
+}
+
+// startLimitResetAfter returns the time until the session start limit is
+// reset.
+//
+// g.mut must be locked.
+func (g *Gateway) startLimitResetAfter() discord.Milliseconds {
+	return discord.Milliseconds(time.Until(g.startLimitReset) / time.Millisecond)
+}
+
+// Identifies returns the number of successful identifies clients made.
+func (g *Gateway) Identifies() int {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	return g.identifies
+}
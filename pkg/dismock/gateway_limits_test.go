@@ -0,0 +1,129 @@
+package dismock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_sendLimit(t *testing.T) {
+	// the identify and the final heartbeat count towards the limit as well
+	testCases := []struct {
+		name       string
+		commands   int
+		heartbeats int
+		expectOp   int
+	}{
+		{name: "within limit", commands: sendLimit - 2, expectOp: int(heartbeatAckOp)},
+		{name: "exceeded", commands: sendLimit - 1, expectOp: -1},
+		{name: "heartbeats", heartbeats: sendLimit - 1, expectOp: -1},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			tMock := new(testing.T)
+
+			m := New(tMock, WithGateway(GatewayConfig{}))
+			defer m.Close()
+
+			conn := dialGateway(t, m)
+			defer conn.Close()
+
+			conn.identify(t)
+
+			for i := 0; i < c.commands; i++ {
+				require.NoError(t, conn.WriteJSON(map[string]interface{}{
+					"op": updatePresenceOp,
+					"d":  gateway.UpdatePresenceCommand{Status: discord.OnlineStatus},
+				}))
+			}
+
+			for i := 0; i < c.heartbeats; i++ {
+				require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": heartbeatOp, "d": 1}))
+				// the acks aren't of interest
+				conn.read(t)
+			}
+
+			require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": heartbeatOp, "d": 1}))
+
+			if c.expectOp >= 0 {
+				p := conn.read(t)
+				assert.Equal(t, c.expectOp, int(p.Op))
+				return
+			}
+
+			_, _, err := conn.ReadMessage()
+
+			var closeErr *websocket.CloseError
+			require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+			assert.Equal(t, closeRateLimited, closeErr.Code)
+
+			assert.True(t, tMock.Failed())
+		})
+	}
+}
+
+func TestGateway_sessionStartLimit(t *testing.T) {
+	t.Run("exceeded", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{
+			Shards:            2,
+			SessionStartLimit: api.SessionStartLimit{Total: 1, MaxConcurrency: 2},
+		}))
+		defer m.Close()
+
+		conn := dialGateway(t, m)
+		defer conn.Close()
+
+		conn.identifyShard(t, &gateway.Shard{0, 2})
+
+		p := conn.read(t)
+		require.Equal(t, "READY", string(p.Type))
+
+		conn2 := dialGateway(t, m)
+		defer conn2.Close()
+
+		conn2.identifyShard(t, &gateway.Shard{1, 2})
+
+		_, _, err := conn2.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+		assert.Equal(t, closeRateLimited, closeErr.Code)
+
+		assert.True(t, tMock.Failed())
+		assert.Equal(t, 1, m.Gateway.Identifies())
+		assert.Equal(t, 0, m.Gateway.BotData().StartLimit.Remaining)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{
+			SessionStartLimit: api.SessionStartLimit{
+				Total:      2,
+				ResetAfter: discord.Milliseconds(50),
+			},
+		}))
+
+		conn := dialGateway(t, m)
+		defer conn.Close()
+
+		conn.identify(t)
+
+		assert.Equal(t, 1, m.Gateway.Identifies())
+		assert.Equal(t, 1, m.Gateway.BotData().StartLimit.Remaining)
+
+		time.Sleep(50 * time.Millisecond)
+
+		assert.Equal(t, 2, m.Gateway.BotData().StartLimit.Remaining)
+	})
+}
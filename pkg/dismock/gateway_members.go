@@ -0,0 +1,187 @@
+package dismock
+
+import (
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// memberChunkSize is the maximum number of members in a Guild Members Chunk
+// event.
+const memberChunkSize = 1000
+
+// sendMemberChunks answers the passed Request Guild Members command with
+// Guild Members Chunk events, using the members and presences of the guilds
+// in the GatewayConfig.
+func (c *gatewayConn) sendMemberChunks(cmd gateway.RequestGuildMembersCommand) {
+	g := c.g
+
+	g.mut.Lock()
+	s := c.session
+	g.mut.Unlock()
+
+	if s == nil {
+		return
+	}
+
+	if !c.checkMemberRequest(s, cmd) {
+		return
+	}
+
+	for _, guildID := range cmd.GuildIDs {
+		for _, chunk := range g.memberChunks(guildID, cmd) {
+			chunk := chunk
+
+			if err := g.dispatch(s, &chunk); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// checkMemberRequest checks if the client has the intents required for the
+// passed Request Guild Members command.
+// If not, the test fails and false is returned.
+func (c *gatewayConn) checkMemberRequest(s *gatewaySession, cmd gateway.RequestGuildMembersCommand) bool {
+	if s.identify.Intents == nil { // user accounts don't use intents
+		return true
+	}
+
+	intents := gateway.Intents(*s.identify.Intents)
+
+	if cmd.Presences && !intents.Has(gateway.IntentGuildPresences) {
+		c.g.t.Errorf("dismock: client requested presences of guild members, but is missing the %s intent",
+			intentNames[gateway.IntentGuildPresences])
+		return false
+	}
+
+	all := len(cmd.UserIDs) == 0 && (cmd.Query == nil || *cmd.Query == "") && cmd.Limit == 0
+	if all && !intents.Has(gateway.IntentGuildMembers) {
+		c.g.t.Errorf("dismock: client requested all guild members, but is missing the %s intent",
+			intentNames[gateway.IntentGuildMembers])
+		return false
+	}
+
+	return true
+}
+
+// memberChunks creates the Guild Members Chunk events answering the passed
+// command for the guild with the passed id.
+//
+// If the guild is not in the Guilds of the GatewayConfig, it is treated as a
+// guild without members.
+func (g *Gateway) memberChunks(
+	guildID discord.GuildID, cmd gateway.RequestGuildMembersCommand,
+) []gateway.GuildMembersChunkEvent {
+	var guild gateway.GuildCreateEvent
+	for _, fixture := range g.config.Guilds {
+		if fixture.ID == guildID {
+			guild = fixture
+			break
+		}
+	}
+
+	members, notFound := requestedMembers(guild.Members, cmd)
+
+	var presences map[discord.UserID]discord.Presence
+	if cmd.Presences {
+		presences = make(map[discord.UserID]discord.Presence, len(guild.Presences))
+		for _, p := range guild.Presences {
+			presences[p.User.ID] = p
+		}
+	}
+
+	count := (len(members) + memberChunkSize - 1) / memberChunkSize
+	if count == 0 { // Discord always sends at least one chunk
+		count = 1
+	}
+
+	chunks := make([]gateway.GuildMembersChunkEvent, count)
+
+	for i := range chunks {
+		chunk := gateway.GuildMembersChunkEvent{
+			GuildID:    guildID,
+			Members:    []discord.Member{},
+			ChunkIndex: i,
+			ChunkCount: count,
+			Nonce:      cmd.Nonce,
+		}
+
+		// like Discord, only the first chunk reports the users that weren't
+		// found
+		if i == 0 {
+			chunk.NotFound = notFound
+		}
+
+		if start := i * memberChunkSize; start < len(members) {
+			end := start + memberChunkSize
+			if end > len(members) {
+				end = len(members)
+			}
+
+			chunk.Members = members[start:end]
+		}
+
+		if cmd.Presences {
+			chunk.Presences = []discord.Presence{}
+
+			for _, m := range chunk.Members {
+				if p, ok := presences[m.User.ID]; ok {
+					chunk.Presences = append(chunk.Presences, p)
+				}
+			}
+		}
+
+		chunks[i] = chunk
+	}
+
+	return chunks
+}
+
+// requestedMembers returns the members requested by the passed command, and
+// the ids of the requested users that aren't members.
+//
+// If the command requests users by id, all of them are returned.
+// Otherwise, all members whose username or nickname start with the query,
+// ignoring case, are returned, but no more than the limit of the command.
+// An empty query matches all members, and a limit of 0 means no limit.
+func requestedMembers(
+	members []discord.Member, cmd gateway.RequestGuildMembersCommand,
+) (requested []discord.Member, notFound []string) {
+	requested = []discord.Member{}
+
+	if len(cmd.UserIDs) > 0 {
+	UserIDs:
+		for _, id := range cmd.UserIDs {
+			for _, m := range members {
+				if m.User.ID == id {
+					requested = append(requested, m)
+					continue UserIDs
+				}
+			}
+
+			notFound = append(notFound, id.String())
+		}
+
+		return requested, notFound
+	}
+
+	var query string
+	if cmd.Query != nil {
+		query = strings.ToLower(*cmd.Query)
+	}
+
+	for _, m := range members {
+		if cmd.Limit > 0 && uint(len(requested)) >= cmd.Limit {
+			break
+		}
+
+		if strings.HasPrefix(strings.ToLower(m.User.Username), query) ||
+			(m.Nick != "" && strings.HasPrefix(strings.ToLower(m.Nick), query)) {
+			requested = append(requested, m)
+		}
+	}
+
+	return requested, nil
+}
@@ -0,0 +1,161 @@
+package dismock
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_memberChunks(t *testing.T) {
+	members := make([]discord.Member, 2500)
+	for i := range members {
+		members[i] = discord.Member{User: discord.User{ID: discord.UserID(i + 1), Username: "user" + strconv.Itoa(i+1)}}
+	}
+
+	members[0].Nick = "abc"
+
+	guild := gateway.GuildCreateEvent{
+		Guild:     discord.Guild{ID: 123},
+		Members:   members,
+		Presences: []discord.Presence{{User: discord.User{ID: 1}, Status: discord.OnlineStatus}},
+	}
+
+	m := New(t, WithGateway(GatewayConfig{Guilds: []gateway.GuildCreateEvent{guild}}))
+	defer m.Close()
+
+	t.Run("all members", func(t *testing.T) {
+		chunks := m.Gateway.memberChunks(123, gateway.RequestGuildMembersCommand{
+			GuildIDs: []discord.GuildID{123},
+			Query:    option.NewString(""),
+			Nonce:    "abc",
+		})
+		require.Len(t, chunks, 3)
+
+		for i, chunk := range chunks {
+			assert.Equal(t, discord.GuildID(123), chunk.GuildID)
+			assert.Equal(t, i, chunk.ChunkIndex)
+			assert.Equal(t, 3, chunk.ChunkCount)
+			assert.Equal(t, "abc", chunk.Nonce)
+			assert.Nil(t, chunk.Presences)
+		}
+
+		assert.Len(t, chunks[0].Members, 1000)
+		assert.Len(t, chunks[1].Members, 1000)
+		assert.Len(t, chunks[2].Members, 500)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		chunks := m.Gateway.memberChunks(123, gateway.RequestGuildMembersCommand{
+			GuildIDs: []discord.GuildID{123},
+			Query:    option.NewString("USER100"),
+			Limit:    5,
+		})
+		require.Len(t, chunks, 1)
+
+		var ids []discord.UserID
+		for _, m := range chunks[0].Members {
+			ids = append(ids, m.User.ID)
+		}
+
+		assert.Equal(t, []discord.UserID{100, 1000, 1001, 1002, 1003}, ids)
+	})
+
+	t.Run("nick", func(t *testing.T) {
+		chunks := m.Gateway.memberChunks(123, gateway.RequestGuildMembersCommand{
+			GuildIDs: []discord.GuildID{123},
+			Query:    option.NewString("ab"),
+			Limit:    10,
+		})
+		require.Len(t, chunks, 1)
+
+		assert.Equal(t, []discord.Member{members[0]}, chunks[0].Members)
+	})
+
+	t.Run("user ids", func(t *testing.T) {
+		chunks := m.Gateway.memberChunks(123, gateway.RequestGuildMembersCommand{
+			GuildIDs:  []discord.GuildID{123},
+			UserIDs:   []discord.UserID{1, 2, 9999},
+			Presences: true,
+		})
+		require.Len(t, chunks, 1)
+
+		assert.Equal(t, members[:2], chunks[0].Members)
+		assert.Equal(t, []string{"9999"}, chunks[0].NotFound)
+		assert.Equal(t, guild.Presences, chunks[0].Presences)
+	})
+
+	t.Run("unknown guild", func(t *testing.T) {
+		chunks := m.Gateway.memberChunks(456, gateway.RequestGuildMembersCommand{
+			GuildIDs: []discord.GuildID{456},
+			Query:    option.NewString(""),
+		})
+
+		expect := []gateway.GuildMembersChunkEvent{
+			{GuildID: 456, Members: []discord.Member{}, ChunkIndex: 0, ChunkCount: 1},
+		}
+		assert.Equal(t, expect, chunks)
+	})
+}
+
+func TestGateway_RequestGuildMembers_chunks(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		guild := gateway.GuildCreateEvent{
+			Guild:   discord.Guild{ID: 123},
+			Members: []discord.Member{{User: discord.User{ID: 1, Username: "abc"}}},
+		}
+
+		m, s := NewSession(t, WithGateway(GatewayConfig{Guilds: []gateway.GuildCreateEvent{guild}}))
+		s.AddIntents(gateway.IntentGuilds | gateway.IntentGuildMembers)
+
+		var chunks []*gateway.GuildMembersChunkEvent
+		s.AddSyncHandler(func(e *gateway.GuildMembersChunkEvent) { chunks = append(chunks, e) })
+
+		openSession(t, s)
+
+		cmd := gateway.RequestGuildMembersCommand{
+			GuildIDs: []discord.GuildID{123},
+			Query:    option.NewString(""),
+			Nonce:    "abc",
+		}
+		m.Gateway.RequestGuildMembers(cmd)
+
+		require.NoError(t, s.Gateway().Send(context.Background(), &cmd))
+
+		m.Gateway.awaitCommands()
+		m.Gateway.Sync()
+
+		require.Len(t, chunks, 1)
+		assert.Equal(t, guild.Members, chunks[0].Members)
+		assert.Equal(t, "abc", chunks[0].Nonce)
+	})
+
+	t.Run("missing intent", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}))
+		s := m.newSession()
+		s.AddIntents(gateway.IntentGuilds)
+
+		openSession(t, s)
+
+		cmd := gateway.RequestGuildMembersCommand{
+			GuildIDs:  []discord.GuildID{123},
+			UserIDs:   []discord.UserID{1},
+			Presences: true,
+		}
+		m.Gateway.RequestGuildMembers(cmd)
+
+		require.NoError(t, s.Gateway().Send(context.Background(), &cmd))
+
+		m.Gateway.awaitCommands()
+		require.NoError(t, s.Close())
+
+		assert.True(t, tMock.Failed())
+	})
+}
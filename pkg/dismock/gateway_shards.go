@@ -0,0 +1,163 @@
+package dismock
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+)
+
+const (
+	// identifyBucketInterval is the interval, in which only a single
+	// identify per rate limit bucket is allowed.
+	identifyBucketInterval = 5 * time.Second
+	// identifyBucketGrace is subtracted from identifyBucketInterval, to
+	// account for identifies sent in time, that arrive late.
+	identifyBucketGrace = 100 * time.Millisecond
+)
+
+// BotData returns the data Discord returns from the Get Gateway Bot endpoint,
+// using the url, the number of shards, and the session start limit of the
+// Gateway.
+//
+// The session start limit reports the remaining identifies and the time
+// until they are reset, at the time BotData is called.
+//
+// To mock the endpoint, use Mocker.BotURL(m.Gateway.BotData()).
+func (g *Gateway) BotData() api.BotData {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	shards := g.config.Shards
+	if shards == 0 {
+		shards = 1
+	}
+
+	g.resetStartLimit()
+
+	limit := g.config.SessionStartLimit
+	limit.ResetAfter = g.startLimitResetAfter()
+
+	return api.BotData{URL: g.URL(), Shards: shards, StartLimit: &limit}
+}
+
+// checkShard checks if the passed shard sent in an identify is valid.
+// If not, the connection is closed and false is returned.
+func (c *gatewayConn) checkShard(shard *gateway.Shard) bool {
+	g := c.g
+
+	if shard == nil {
+		if g.config.Shards > 1 {
+			g.t.Errorf("dismock: client identified without shard, but must use %d shards", g.config.Shards)
+			c.close(closeShardingRequired)
+			return false
+		}
+
+		return true
+	}
+
+	if shard.ShardID() < 0 || shard.NumShards() < 1 || shard.ShardID() >= shard.NumShards() {
+		g.t.Errorf("dismock: client identified with invalid shard %v", *shard)
+		c.close(closeInvalidShard)
+		return false
+	}
+
+	if g.config.Shards > 0 && shard.NumShards() != g.config.Shards {
+		g.t.Errorf("dismock: client identified with shard %v, but must use %d shards", *shard, g.config.Shards)
+		c.close(closeInvalidShard)
+		return false
+	}
+
+	return true
+}
+
+// identifyAllowed checks if the max concurrency of the session start limit
+// allows an identify for the passed shard.
+// If not, the test fails and false is returned.
+//
+// Identifies are rate limited in buckets, with a bucket's rate limit key
+// being shard_id % max_concurrency.
+func (g *Gateway) identifyAllowed(shard *gateway.Shard) bool {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	var shardID int
+	if shard != nil {
+		shardID = shard.ShardID()
+	}
+
+	key := shardID % g.config.SessionStartLimit.MaxConcurrency
+
+	now := time.Now()
+
+	if last, ok := g.buckets[key]; ok {
+		if since := now.Sub(last); since < identifyBucketInterval-identifyBucketGrace {
+			g.t.Errorf("dismock: shard %d identified %s after the last identify with rate limit key %d, "+
+				"but must wait %s", shardID, since, key, identifyBucketInterval)
+			return false
+		}
+	}
+
+	g.buckets[key] = now
+	return true
+}
+
+// route returns the session responsible for the passed event.
+// If there is none, the test fails and nil is returned.
+func (g *Gateway) route(e gateway.Event) *gatewaySession {
+	var shardID int
+
+	// if the event can't be encoded, dispatch will report it
+	data, _ := json.Marshal(e)
+	guildID := eventGuildID(e.EventType(), data)
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if len(g.shards) == 0 {
+		if g.current == nil {
+			g.t.Error("dismock: no client is connected to the gateway")
+		}
+
+		return g.current
+	}
+
+	if guildID.IsValid() {
+		shardID = int(uint64(guildID>>22) % uint64(g.numShards))
+	}
+
+	s := g.shards[shardID]
+	if s == nil {
+		g.t.Errorf("dismock: no client is connected to the gateway as shard %d", shardID)
+	}
+
+	return s
+}
+
+// eventGuildID returns the id of the guild the event with the passed type
+// and data belongs to.
+// If it doesn't belong to a guild, 0 is returned.
+func eventGuildID(eventType ws.EventType, data []byte) discord.GuildID {
+	var fields struct {
+		GuildID discord.GuildID `json:"guild_id"`
+		ID      discord.GuildID `json:"id"`
+	}
+
+	// ignore the error, events with invalid fields simply don't belong to a
+	// guild
+	_ = json.Unmarshal(data, &fields)
+
+	if fields.GuildID.IsValid() {
+		return fields.GuildID
+	}
+
+	switch eventType {
+	case "GUILD_CREATE", "GUILD_UPDATE", "GUILD_DELETE":
+		return fields.ID
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,171 @@
+package dismock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway_BotData(t *testing.T) {
+	m := New(t, WithGateway(GatewayConfig{}))
+
+	expect := api.BotData{
+		URL:    m.Gateway.URL(),
+		Shards: 1,
+		StartLimit: &api.SessionStartLimit{
+			Total:          1000,
+			Remaining:      1000,
+			ResetAfter:     discord.Milliseconds(24 * time.Hour / time.Millisecond),
+			MaxConcurrency: 1,
+		},
+	}
+
+	actual := m.Gateway.BotData()
+	require.NotNil(t, actual.StartLimit)
+
+	// some time may have passed since the Gateway was started
+	assert.InDelta(t, int64(expect.StartLimit.ResetAfter), int64(actual.StartLimit.ResetAfter), 1000)
+	actual.StartLimit.ResetAfter = expect.StartLimit.ResetAfter
+
+	assert.Equal(t, expect, actual)
+}
+
+func TestNewShardManager(t *testing.T) {
+	m, mgr := NewShardManager(t, WithGateway(GatewayConfig{
+		Shards:            2,
+		SessionStartLimit: api.SessionStartLimit{MaxConcurrency: 2},
+	}))
+	require.Equal(t, 2, mgr.NumShards())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, mgr.Open(ctx))
+
+	m.Gateway.WaitForIdentifies(2)
+
+	received := make([][]discord.GuildID, mgr.NumShards())
+
+	for i := 0; i < mgr.NumShards(); i++ {
+		i := i
+
+		mgr.Shard(i).(*session.Session).AddSyncHandler(func(e *gateway.TypingStartEvent) {
+			received[i] = append(received[i], e.GuildID)
+		})
+	}
+
+	m.Gateway.Dispatch(
+		&gateway.TypingStartEvent{GuildID: 1 << 22},
+		&gateway.TypingStartEvent{GuildID: 2 << 22},
+		&gateway.TypingStartEvent{GuildID: 3 << 22},
+		&gateway.TypingStartEvent{}, // direct message
+	)
+	m.Gateway.Sync()
+
+	assert.Equal(t, []discord.GuildID{2 << 22, 0}, received[0])
+	assert.Equal(t, []discord.GuildID{1 << 22, 3 << 22}, received[1])
+}
+
+func TestGateway_shards(t *testing.T) {
+	t.Run("invalid shard", func(t *testing.T) {
+		testCases := []struct {
+			name      string
+			shard     *gateway.Shard
+			expectErr int
+		}{
+			{name: "missing", shard: nil, expectErr: closeShardingRequired},
+			{name: "wrong number of shards", shard: &gateway.Shard{0, 3}, expectErr: closeInvalidShard},
+			{name: "id out of range", shard: &gateway.Shard{2, 2}, expectErr: closeInvalidShard},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				tMock := new(testing.T)
+
+				m := New(tMock, WithGateway(GatewayConfig{Shards: 2}))
+				defer m.Close()
+
+				conn := dialGateway(t, m)
+				defer conn.Close()
+
+				conn.identifyShard(t, c.shard)
+
+				_, _, err := conn.ReadMessage()
+
+				var closeErr *websocket.CloseError
+				require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+				assert.Equal(t, c.expectErr, closeErr.Code)
+
+				assert.True(t, tMock.Failed())
+			})
+		}
+	})
+
+	t.Run("max concurrency", func(t *testing.T) {
+		testCases := []struct {
+			name           string
+			maxConcurrency int
+			expectOp       int
+		}{
+			{name: "same bucket", maxConcurrency: 1, expectOp: int(invalidSessionOp)},
+			{name: "different buckets", maxConcurrency: 2, expectOp: int(dispatchOp)},
+		}
+
+		for _, c := range testCases {
+			c := c
+
+			t.Run(c.name, func(t *testing.T) {
+				tMock := new(testing.T)
+
+				m := New(tMock, WithGateway(GatewayConfig{
+					Shards:            2,
+					SessionStartLimit: api.SessionStartLimit{MaxConcurrency: c.maxConcurrency},
+				}))
+				defer m.Close()
+
+				for i := 0; i < 2; i++ {
+					conn := dialGateway(t, m)
+					defer conn.Close()
+
+					conn.identifyShard(t, &gateway.Shard{i, 2})
+
+					p := conn.read(t)
+					if i == 0 {
+						assert.Equal(t, dispatchOp, p.Op)
+					} else {
+						assert.Equal(t, c.expectOp, int(p.Op))
+					}
+				}
+
+				assert.Equal(t, c.expectOp == int(invalidSessionOp), tMock.Failed())
+			})
+		}
+	})
+
+	t.Run("shard not connected", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{Shards: 2}))
+		defer m.Close()
+
+		conn := dialGateway(t, m)
+		defer conn.Close()
+
+		conn.identifyShard(t, &gateway.Shard{0, 2})
+		conn.read(t) // ready
+
+		m.Gateway.Dispatch(&gateway.TypingStartEvent{GuildID: 1 << 22})
+		assert.True(t, tMock.Failed())
+	})
+}
@@ -0,0 +1,362 @@
+package dismock
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mavolin/dismock/v3/internal/etf"
+)
+
+func TestWithGateway(t *testing.T) {
+	t.Run("session", func(t *testing.T) {
+		user := discord.User{ID: 123, Username: "abc", Bot: true}
+
+		m, s := NewSession(t, WithGateway(GatewayConfig{User: user}), WithToken(BotToken, "abc"))
+		require.NotNil(t, m.Gateway)
+
+		ready := make(chan *gateway.ReadyEvent, 1)
+		s.AddHandler(ready)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, s.Open(ctx))
+
+		select {
+		case e := <-ready:
+			assert.Equal(t, user, e.User)
+			assert.NotEmpty(t, e.SessionID)
+		case <-ctx.Done():
+			require.Fail(t, "no ready event received")
+		}
+	})
+
+	t.Run("state", func(t *testing.T) {
+		_, s := NewState(t, WithGateway(GatewayConfig{}))
+		openSession(t, s.Session)
+	})
+
+	t.Run("authentication failed", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}), WithToken(BotToken, "abc"))
+
+		s := m.newSession()
+		s.Gateway().SetState(gateway.State{Identifier: gateway.DefaultIdentifier("def")})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := s.Open(ctx)
+
+		var closeErr *ws.CloseEvent
+		require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+		assert.Equal(t, closeAuthenticationError, closeErr.Code)
+	})
+
+	t.Run("not authenticated", func(t *testing.T) {
+		m := New(t, WithGateway(GatewayConfig{}))
+
+		c, _, err := websocket.DefaultDialer.Dial(m.Gateway.URL(), nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		var hello struct {
+			Op   ws.OpCode          `json:"op"`
+			Data gateway.HelloEvent `json:"d"`
+		}
+		require.NoError(t, c.ReadJSON(&hello))
+		assert.Equal(t, helloOp, hello.Op)
+		assert.Equal(t, discord.Milliseconds(defaultHeartbeat/time.Millisecond), hello.Data.HeartbeatInterval)
+
+		require.NoError(t, c.WriteJSON(map[string]interface{}{"op": 3, "d": nil}))
+
+		_, _, err = c.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+		assert.Equal(t, closeNotAuthenticated, closeErr.Code)
+	})
+}
+
+func TestGateway_Dispatch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{}))
+		openSession(t, s)
+
+		var actual []string
+		s.AddSyncHandler(func(e *gateway.MessageCreateEvent) {
+			actual = append(actual, e.Content)
+		})
+
+		m.Gateway.Dispatch(
+			&gateway.MessageCreateEvent{Message: discord.Message{ID: 1, Content: "abc"}},
+			&gateway.MessageCreateEvent{Message: discord.Message{ID: 2, Content: "def"}},
+		)
+		m.Gateway.Sync()
+
+		assert.Equal(t, []string{"abc", "def"}, actual)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		t.Run("no client", func(t *testing.T) {
+			tMock := new(testing.T)
+
+			m := New(tMock, WithGateway(GatewayConfig{}))
+			defer m.Close()
+
+			m.Gateway.Dispatch(&gateway.MessageCreateEvent{})
+			assert.True(t, tMock.Failed())
+		})
+
+		t.Run("not a dispatch", func(t *testing.T) {
+			tMock := new(testing.T)
+
+			m := New(tMock, WithGateway(GatewayConfig{}))
+			s := m.newSession()
+			openSession(t, s)
+
+			defer m.Close()
+			defer s.Close()
+
+			heartbeat := gateway.HeartbeatCommand(1)
+			m.Gateway.Dispatch(&heartbeat)
+			assert.True(t, tMock.Failed())
+		})
+	})
+}
+
+// invalidEvent is an event that can't be decoded by arikawa.
+type invalidEvent struct {
+	Content int `json:"content"`
+}
+
+func (*invalidEvent) Op() ws.OpCode { return dispatchOp }
+
+func (*invalidEvent) EventType() ws.EventType { return "MESSAGE_CREATE" }
+
+func TestGateway_Sync(t *testing.T) {
+	t.Run("state", func(t *testing.T) {
+		m, s := NewState(t, WithGateway(GatewayConfig{}))
+		openSession(t, s.Session)
+
+		var handled bool
+		s.AddSyncHandler(func(*gateway.TypingStartEvent) {
+			time.Sleep(50 * time.Millisecond)
+			handled = true
+		})
+
+		m.Gateway.Dispatch(&gateway.TypingStartEvent{ChannelID: 123})
+		m.Gateway.Sync()
+
+		assert.True(t, handled)
+	})
+
+	t.Run("interface handler", func(t *testing.T) {
+		m, s := NewSession(t, WithGateway(GatewayConfig{}))
+		openSession(t, s)
+
+		var typed, iface int32
+
+		s.AddSyncHandler(func(gateway.Event) {
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt32(&iface, 1)
+		})
+		s.AddSyncHandler(func(*gateway.TypingStartEvent) {
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt32(&typed, 1)
+		})
+
+		m.Gateway.Dispatch(&gateway.TypingStartEvent{ChannelID: 123})
+		m.Gateway.Sync()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&typed))
+
+		// handlers for all events are called after the Gateway's handler, and
+		// are therefore not waited for
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&iface) == 1 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{Timeout: 100 * time.Millisecond}))
+		s := m.newSession()
+		s.AddSyncHandler(m.Gateway.handle)
+		openSession(t, s)
+
+		defer m.Close()
+		defer s.Close()
+
+		m.Gateway.Dispatch(new(invalidEvent))
+		m.Gateway.Sync()
+
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func openSession(t *testing.T, s *session.Session) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, s.Open(ctx))
+}
+
+// testGatewayConn is a connection to the Gateway or to a voice server, that
+// decompresses and decodes the payloads it reads, if needed.
+type testGatewayConn struct {
+	*websocket.Conn
+	// etf indicates whether payloads are etf encoded.
+	etf bool
+	// zlib indicates whether payloads are zlib-stream compressed.
+	zlib bool
+
+	// messages is the number of messages read.
+	messages int
+	// compressed is the zlib-stream read so far.
+	compressed []byte
+	// decompressed is the number of decompressed bytes read so far.
+	decompressed int
+}
+
+// dialGateway connects to the Gateway of the passed Mocker and reads the
+// Hello event.
+func dialGateway(t *testing.T, m *Mocker) *testGatewayConn {
+	t.Helper()
+
+	return dialGatewayURL(t, m.Gateway.URL())
+}
+
+// dialGatewayURL connects to the Gateway at the passed url, using the
+// encoding and compression specified in its query, and reads the Hello
+// event.
+func dialGatewayURL(t *testing.T, rawURL string) *testGatewayConn {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial(rawURL, nil)
+	require.NoError(t, err)
+
+	c := &testGatewayConn{
+		Conn: conn,
+		etf:  u.Query().Get("encoding") == "etf",
+		zlib: u.Query().Get("compress") == "zlib-stream",
+	}
+
+	p := c.read(t)
+	require.Equal(t, helloOp, p.Op)
+
+	return c
+}
+
+// testReadyEvent contains the fields of the Ready event relevant to
+// resuming.
+type testReadyEvent struct {
+	SessionID        string `json:"session_id"`
+	ResumeGatewayURL string `json:"resume_gateway_url"`
+}
+
+// identify identifies and returns the Ready event.
+func (c *testGatewayConn) identify(t *testing.T) testReadyEvent {
+	t.Helper()
+
+	require.NoError(t, c.WriteJSON(map[string]interface{}{
+		"op": identifyOp,
+		"d":  map[string]interface{}{"token": "", "properties": map[string]string{}},
+	}))
+
+	p := c.read(t)
+	require.Equal(t, "READY", string(p.Type))
+
+	var ready testReadyEvent
+	require.NoError(t, json.Unmarshal(p.Data, &ready))
+
+	return ready
+}
+
+// identifyShard identifies using the passed shard, without reading the
+// Ready event.
+func (c *testGatewayConn) identifyShard(t *testing.T, shard *gateway.Shard) {
+	t.Helper()
+
+	require.NoError(t, c.WriteJSON(map[string]interface{}{
+		"op": identifyOp,
+		"d":  gateway.IdentifyCommand{Shard: shard},
+	}))
+}
+
+// read reads the next payload.
+func (c *testGatewayConn) read(t *testing.T) gatewayPayload {
+	t.Helper()
+
+	msgType, data, err := c.ReadMessage()
+	require.NoError(t, err)
+
+	c.messages++
+
+	if c.zlib {
+		assert.Equal(t, websocket.BinaryMessage, msgType)
+		data = c.decompress(t, data)
+	}
+
+	if c.etf {
+		assert.Equal(t, websocket.BinaryMessage, msgType)
+
+		data, err = etf.ToJSON(data)
+		require.NoError(t, err)
+	}
+
+	var p gatewayPayload
+	require.NoError(t, json.Unmarshal(data, &p))
+
+	return p
+}
+
+// decompress reads messages, until the passed message is completed to a
+// full frame, and returns the decompressed frame.
+//
+// Since the zlib reader can't be resumed once it reached the end of the data
+// received so far, the whole stream is decompressed every time.
+func (c *testGatewayConn) decompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	c.compressed = append(c.compressed, data...)
+
+	for !bytes.HasSuffix(c.compressed, []byte{0, 0, 0xff, 0xff}) {
+		_, data, err := c.ReadMessage()
+		require.NoError(t, err)
+
+		c.messages++
+		c.compressed = append(c.compressed, data...)
+	}
+
+	z, err := zlib.NewReader(bytes.NewReader(c.compressed))
+	require.NoError(t, err)
+
+	decompressed, err := ioutil.ReadAll(z)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	frame := decompressed[c.decompressed:]
+	c.decompressed = len(decompressed)
+
+	return frame
+}
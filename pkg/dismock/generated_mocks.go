@@ -213,22 +213,28 @@ func (m *Mocker) Channels(guildID discord.GuildID, _ret []discord.Channel) {
 }
 
 // CreateChannel mocks api.Client.CreateChannel.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) CreateChannel(data api.CreateChannelData, _ret discord.Channel) {
 	m.MockAPI("CreateChannel", http.MethodPost, "guilds/"+_ret.GuildID.String()+"/channels",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // MoveChannels mocks api.Client.MoveChannels.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) MoveChannels(guildID discord.GuildID, data api.MoveChannelsData) {
 	m.MockAPI("MoveChannels", http.MethodPatch, "guilds/"+guildID.String()+"/channels",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
@@ -241,38 +247,50 @@ func (m *Mocker) Channel(_ret discord.Channel) {
 }
 
 // ModifyChannel mocks api.Client.ModifyChannel.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyChannel(channelID discord.ChannelID, data api.ModifyChannelData) {
 	m.MockAPI("ModifyChannel", http.MethodPatch, "channels/"+channelID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
 // DeleteChannel mocks api.Client.DeleteChannel.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteChannel(channelID discord.ChannelID, reason api.AuditLogReason) {
 	m.MockAPI("DeleteChannel", http.MethodDelete, "channels/"+channelID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
 // EditChannelPermission mocks api.Client.EditChannelPermission.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) EditChannelPermission(channelID discord.ChannelID, overwriteID discord.Snowflake, data api.EditChannelPermissionData) {
 	m.MockAPI("EditChannelPermission", http.MethodPut, "channels/"+channelID.String()+"/permissions/"+overwriteID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
 // DeleteChannelPermission mocks api.Client.DeleteChannelPermission.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteChannelPermission(channelID discord.ChannelID, overwriteID discord.Snowflake, reason api.AuditLogReason) {
 	m.MockAPI("DeleteChannelPermission", http.MethodDelete, "channels/"+channelID.String()+"/permissions/"+overwriteID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -294,18 +312,24 @@ func (m *Mocker) PinnedMessages(channelID discord.ChannelID, _ret []discord.Mess
 }
 
 // PinMessage mocks api.Client.PinMessage.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) PinMessage(channelID discord.ChannelID, messageID discord.MessageID, reason api.AuditLogReason) {
 	m.MockAPI("PinMessage", http.MethodPut, "channels/"+channelID.String()+"/pins/"+messageID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
 // UnpinMessage mocks api.Client.UnpinMessage.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) UnpinMessage(channelID discord.ChannelID, messageID discord.MessageID, reason api.AuditLogReason) {
 	m.MockAPI("UnpinMessage", http.MethodDelete, "channels/"+channelID.String()+"/pins/"+messageID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -331,24 +355,30 @@ func (m *Mocker) RemoveRecipient(channelID discord.ChannelID, userID discord.Use
 }
 
 // StartThreadWithMessage mocks api.Client.StartThreadWithMessage.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) StartThreadWithMessage(messageID discord.MessageID, data api.StartThreadData, _ret discord.Channel) {
 	m.MockAPI("StartThreadWithMessage", http.MethodPost, "channels/"+_ret.ParentID.String()+"/messages/"+messageID.String()+"/threads",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // StartThreadWithoutMessage mocks api.Client.StartThreadWithoutMessage.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) StartThreadWithoutMessage(data api.StartThreadData, _ret discord.Channel) {
 	m.MockAPI("StartThreadWithoutMessage", http.MethodPost, "channels/"+_ret.ParentID.String()+"/threads",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -494,32 +524,41 @@ func (m *Mocker) Emoji(guildID discord.GuildID, _ret discord.Emoji) {
 }
 
 // CreateEmoji mocks api.Client.CreateEmoji.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) CreateEmoji(guildID discord.GuildID, data api.CreateEmojiData, _ret discord.Emoji) {
 	m.MockAPI("CreateEmoji", http.MethodPost, "guilds/"+guildID.String()+"/emojis",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // ModifyEmoji mocks api.Client.ModifyEmoji.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyEmoji(guildID discord.GuildID, emojiID discord.EmojiID, data api.ModifyEmojiData) {
 	m.MockAPI("ModifyEmoji", http.MethodPatch, "guilds/"+guildID.String()+"/emojis/"+emojiID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
 // DeleteEmoji mocks api.Client.DeleteEmoji.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteEmoji(guildID discord.GuildID, emojiID discord.EmojiID, reason api.AuditLogReason) {
 	m.MockAPI("DeleteEmoji", http.MethodDelete, "guilds/"+guildID.String()+"/emojis/"+emojiID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -579,12 +618,15 @@ func (m *Mocker) LeaveGuild(id discord.GuildID) {
 }
 
 // ModifyGuild mocks api.Client.ModifyGuild.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyGuild(data api.ModifyGuildData, _ret discord.Guild) {
 	m.MockAPI("ModifyGuild", http.MethodPatch, "guilds/"+_ret.ID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -671,12 +713,15 @@ func (m *Mocker) GuildWidgetSettings(guildID discord.GuildID, _ret discord.Guild
 }
 
 // ModifyGuildWidget mocks api.Client.ModifyGuildWidget.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyGuildWidget(guildID discord.GuildID, data api.ModifyGuildWidgetData, _ret discord.GuildWidgetSettings) {
 	m.MockAPI("ModifyGuildWidget", http.MethodPatch, "guilds/"+guildID.String()+"/widget",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -809,12 +854,15 @@ func (m *Mocker) GuildInvites(guildID discord.GuildID, _ret []discord.Invite) {
 }
 
 // CreateInvite mocks api.Client.CreateInvite.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) CreateInvite(channelID discord.ChannelID, data api.CreateInviteData, _ret discord.Invite) {
 	m.MockAPI("CreateInvite", http.MethodPost, "channels/"+channelID.String()+"/invites",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -829,10 +877,13 @@ func (m *Mocker) JoinInvite(_ret api.JoinedInvite) {
 }
 
 // DeleteInvite mocks api.Client.DeleteInvite.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteInvite(reason api.AuditLogReason, _ret discord.Invite) {
 	m.MockAPI("DeleteInvite", http.MethodDelete, "invites/"+_ret.Code,
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -901,12 +952,15 @@ func (m *Mocker) AddMember(guildID discord.GuildID, data api.AddMemberData, _ret
 }
 
 // ModifyMember mocks api.Client.ModifyMember.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyMember(guildID discord.GuildID, userID discord.UserID, data api.ModifyMemberData) {
 	m.MockAPI("ModifyMember", http.MethodPatch, "guilds/"+guildID.String()+"/members/"+userID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
@@ -928,6 +982,9 @@ func (m *Mocker) PruneCount(guildID discord.GuildID, data api.PruneCountData, _r
 }
 
 // Prune mocks api.Client.Prune.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) Prune(guildID discord.GuildID, data api.PruneData, _ret uint) {
 	m.MockAPI("Prune", http.MethodPost, "guilds/"+guildID.String()+"/prune",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
@@ -937,7 +994,7 @@ func (m *Mocker) Prune(guildID discord.GuildID, data api.PruneData, _ret uint) {
 
 			check.Query(_t, _values, _r.URL.Query())
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			_wrappedResp := struct {
 				Resp uint `json:"pruned"`
@@ -947,10 +1004,13 @@ func (m *Mocker) Prune(guildID discord.GuildID, data api.PruneData, _ret uint) {
 }
 
 // Kick mocks api.Client.Kick.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) Kick(guildID discord.GuildID, userID discord.UserID, reason api.AuditLogReason) {
 	m.MockAPI("Kick", http.MethodDelete, "guilds/"+guildID.String()+"/members/"+userID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -975,6 +1035,9 @@ func (m *Mocker) GetBan(guildID discord.GuildID, _ret discord.Ban) {
 }
 
 // Ban mocks api.Client.Ban.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) Ban(guildID discord.GuildID, userID discord.UserID, data api.BanData) {
 	m.MockAPI("Ban", http.MethodPut, "guilds/"+guildID.String()+"/bans/"+userID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
@@ -984,15 +1047,18 @@ func (m *Mocker) Ban(guildID discord.GuildID, userID discord.UserID, data api.Ba
 
 			check.Query(_t, _values, _r.URL.Query())
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
 // Unban mocks api.Client.Unban.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) Unban(guildID discord.GuildID, userID discord.UserID, reason api.AuditLogReason) {
 	m.MockAPI("Unban", http.MethodDelete, "guilds/"+guildID.String()+"/bans/"+userID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -1017,10 +1083,13 @@ func (m *Mocker) CrosspostMessage(channelID discord.ChannelID, messageID discord
 }
 
 // DeleteMessage mocks api.Client.DeleteMessage.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteMessage(channelID discord.ChannelID, messageID discord.MessageID, reason api.AuditLogReason) {
 	m.MockAPI("DeleteMessage", http.MethodDelete, "channels/"+channelID.String()+"/messages/"+messageID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -1048,18 +1117,24 @@ func (m *Mocker) DeleteAllReactions(channelID discord.ChannelID, messageID disco
 // =====================================================================================
 
 // AddRole mocks api.Client.AddRole.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) AddRole(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID, data api.AddRoleData) {
 	m.MockAPI("AddRole", http.MethodPut, "guilds/"+guildID.String()+"/members/"+userID.String()+"/roles/"+roleID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
 // RemoveRole mocks api.Client.RemoveRole.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) RemoveRole(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID, reason api.AuditLogReason) {
 	m.MockAPI("RemoveRole", http.MethodDelete, "guilds/"+guildID.String()+"/members/"+userID.String()+"/roles/"+roleID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -1076,18 +1151,24 @@ func (m *Mocker) Roles(guildID discord.GuildID, _ret []discord.Role) {
 }
 
 // CreateRole mocks api.Client.CreateRole.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) CreateRole(guildID discord.GuildID, data api.CreateRoleData, _ret discord.Role) {
 	m.MockAPI("CreateRole", http.MethodPost, "guilds/"+guildID.String()+"/roles",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // MoveRoles mocks api.Client.MoveRoles.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) MoveRoles(guildID discord.GuildID, data api.MoveRolesData, _ret []discord.Role) {
 	if _ret == nil {
 		_ret = []discord.Role{}
@@ -1095,29 +1176,35 @@ func (m *Mocker) MoveRoles(guildID discord.GuildID, data api.MoveRolesData, _ret
 
 	m.MockAPI("MoveRoles", http.MethodPatch, "guilds/"+guildID.String()+"/roles",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // ModifyRole mocks api.Client.ModifyRole.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyRole(guildID discord.GuildID, data api.ModifyRoleData, _ret discord.Role) {
 	m.MockAPI("ModifyRole", http.MethodPatch, "guilds/"+guildID.String()+"/roles/"+_ret.ID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // DeleteRole mocks api.Client.DeleteRole.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteRole(guildID discord.GuildID, roleID discord.RoleID, reason api.AuditLogReason) {
 	m.MockAPI("DeleteRole", http.MethodDelete, "guilds/"+guildID.String()+"/roles/"+roleID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -1180,24 +1267,30 @@ func (m *Mocker) ListScheduledEvents(guildID discord.GuildID, withUserCount bool
 }
 
 // CreateScheduledEvent mocks api.Client.CreateScheduledEvent.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) CreateScheduledEvent(guildID discord.GuildID, reason api.AuditLogReason, data api.CreateScheduledEventData, _ret discord.GuildScheduledEvent) {
 	m.MockAPI("CreateScheduledEvent", http.MethodPost, "guilds/"+guildID.String()+"/scheduled-events",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // EditScheduledEvent mocks api.Client.EditScheduledEvent.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) EditScheduledEvent(guildID discord.GuildID, eventID discord.EventID, reason api.AuditLogReason, data api.EditScheduledEventData, _ret discord.GuildScheduledEvent) {
 	m.MockAPI("EditScheduledEvent", http.MethodPatch, "guilds/"+guildID.String()+"/scheduled-events/"+eventID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -1251,32 +1344,41 @@ func (m *Mocker) Search(guildID discord.GuildID, data api.SearchData, _ret api.S
 // =====================================================================================
 
 // CreateStageInstance mocks api.Client.CreateStageInstance.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) CreateStageInstance(data api.CreateStageInstanceData, _ret discord.StageInstance) {
 	m.MockAPI("CreateStageInstance", http.MethodPost, "stage-instances/",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
 }
 
 // UpdateStageInstance mocks api.Client.UpdateStageInstance.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) UpdateStageInstance(channelID discord.ChannelID, data api.UpdateStageInstanceData) {
 	m.MockAPI("UpdateStageInstance", http.MethodPatch, "stage-instances/"+channelID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 		})
 }
 
 // DeleteStageInstance mocks api.Client.DeleteStageInstance.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) DeleteStageInstance(channelID discord.ChannelID, reason api.AuditLogReason) {
 	m.MockAPI("DeleteStageInstance", http.MethodDelete, "stage-instances/"+channelID.String(),
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
-			check.Header(_t, reason.Header(), _r.Header)
+			check.AuditLogReason(_t, reason.Header(), _r.Header)
 		})
 }
 
@@ -1301,12 +1403,15 @@ func (m *Mocker) Me(_ret discord.User) {
 }
 
 // ModifyCurrentUser mocks api.Client.ModifyCurrentUser.
+//
+// The audit log reason is expected in plain text, see the section about
+// audit log reasons in the package documentation.
 func (m *Mocker) ModifyCurrentUser(data api.ModifyCurrentUserData, _ret discord.User) {
 	m.MockAPI("ModifyCurrentUser", http.MethodPatch, "users/@me",
 		func(_w http.ResponseWriter, _r *http.Request, _t testing.TInterface) {
 			check.JSON(_t, data, _r.Body)
 
-			check.Header(_t, data.Header(), _r.Header)
+			check.AuditLogReason(_t, data.Header(), _r.Header)
 
 			check.WriteJSON(_t, _w, _ret)
 		})
@@ -0,0 +1,55 @@
+package dismock
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// member.go
+// =====================================================================================
+
+func TestMocker_Kick(t *testing.T) {
+	testCases := []struct {
+		name   string
+		reason string
+	}{
+		{name: "ascii", reason: "abc"},
+		{name: "non-ascii", reason: "äbc"},
+		{name: "percent", reason: "100%"},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			m, s := NewSession(t)
+
+			// the mock expects the reason in plain text, while arikawa
+			// expects it to be URL-encoded already
+			m.Kick(123, 456, api.AuditLogReason(c.reason))
+
+			err := s.Kick(123, 456, api.AuditLogReason(url.PathEscape(c.reason)))
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("not url-encoded", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m, s := NewSession(tMock)
+
+		m.Kick(123, 456, "äbc")
+
+		err := s.Kick(123, 456, "äbc")
+		require.NoError(t, err)
+
+		m.Close() // prevent m.eval from failing
+
+		assert.True(t, tMock.Failed())
+	})
+}
@@ -0,0 +1,384 @@
+package dismock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+// BotPermissions describes the guilds, roles and channels of the bot, and is
+// used to compute the permissions the bot has when making a request.
+type BotPermissions struct {
+	// BotID is the id of the bot.
+	BotID discord.UserID
+	// Guilds are the guilds the bot is in.
+	// The OwnerID and Roles of each guild must be set, the permissions of
+	// the @everyone role are taken from the role with the id of the guild.
+	Guilds []discord.Guild
+	// Roles are the ids of the roles the bot has, sorted by guild.
+	Roles map[discord.GuildID][]discord.RoleID
+	// Channels are the guild channels the bot knows about.
+	// The GuildID and Overwrites of each channel must be set.
+	// Threads inherit the overwrites of their parent, which therefore must
+	// also be included.
+	Channels []discord.Channel
+	// Messages are messages, whose author is known.
+	// Messages not included here are assumed to be sent by someone other
+	// than the bot.
+	Messages []discord.Message
+}
+
+var (
+	// missingPermissionsError is the error Discord responds with, if the
+	// bot lacks a permission required by the endpoint.
+	missingPermissionsError = httputil.HTTPError{
+		Status:  http.StatusForbidden,
+		Code:    50013,
+		Message: "Missing Permissions",
+	}
+	// missingAccessError is the error Discord responds with, if the bot
+	// can't view the channel it makes a request to.
+	missingAccessError = httputil.HTTPError{
+		Status:  http.StatusForbidden,
+		Code:    50001,
+		Message: "Missing Access",
+	}
+)
+
+type (
+	// permissionState is the state used to compute the permissions of the
+	// bot.
+	permissionState struct {
+		botID          discord.UserID
+		guilds         map[discord.GuildID]discord.Guild
+		roles          map[discord.GuildID][]discord.RoleID
+		channels       map[discord.ChannelID]discord.Channel
+		messageAuthors map[discord.MessageID]discord.UserID
+	}
+
+	// permissionRule maps the endpoints matching method and path to the
+	// permissions they require.
+	permissionRule struct {
+		// route is the route of the endpoints.
+		// The second segment of its path is always the id of the channel or
+		// guild the permissions are computed for.
+		route apiRoute
+		// perms are the permissions required.
+		perms discord.Permissions
+		// permFunc, if set, is used instead of perms, to compute the
+		// required permissions depending on the request.
+		permFunc func(s *permissionState, r *http.Request, path []string) discord.Permissions
+	}
+)
+
+// permissionRules are the permissionRules of all endpoints that require
+// permissions.
+// Endpoints that don't match any rule require no permissions, apart from
+// being able to view the channel for channel endpoints.
+var permissionRules = []permissionRule{
+	// channel endpoints
+	{route: newAPIRoute(http.MethodPatch, "channels/*"), perms: discord.PermissionManageChannels},
+	{route: newAPIRoute(http.MethodDelete, "channels/*"), perms: discord.PermissionManageChannels},
+	{route: newAPIRoute(http.MethodGet, "channels/*/messages"), perms: discord.PermissionReadMessageHistory},
+	{route: newAPIRoute(http.MethodGet, "channels/*/messages/*"), perms: discord.PermissionReadMessageHistory},
+	{route: newAPIRoute(http.MethodPost, "channels/*/messages"), permFunc: sendMessagePermissions},
+	{route: newAPIRoute(http.MethodPost, "channels/*/typing"), permFunc: sendMessagePermissions},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/messages/*"), permFunc: deleteMessagePermissions},
+	{route: newAPIRoute(http.MethodPost, "channels/*/messages/bulk-delete"), perms: discord.PermissionManageMessages},
+	{
+		route: newAPIRoute(http.MethodPut, "channels/*/messages/*/reactions/*/@me"),
+		perms: discord.PermissionAddReactions | discord.PermissionReadMessageHistory,
+	},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/messages/*/reactions/*/@me")},
+	{
+		route: newAPIRoute(http.MethodDelete, "channels/*/messages/*/reactions/*/*"),
+		perms: discord.PermissionManageMessages,
+	},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/messages/*/reactions/*"), perms: discord.PermissionManageMessages},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/messages/*/reactions"), perms: discord.PermissionManageMessages},
+	{route: newAPIRoute(http.MethodPost, "channels/*/messages/*/crosspost"), permFunc: crosspostPermissions},
+	{route: newAPIRoute(http.MethodPut, "channels/*/pins/*"), perms: discord.PermissionManageMessages},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/pins/*"), perms: discord.PermissionManageMessages},
+	{route: newAPIRoute(http.MethodPut, "channels/*/permissions/*"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodDelete, "channels/*/permissions/*"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodGet, "channels/*/invites"), perms: discord.PermissionManageChannels},
+	{route: newAPIRoute(http.MethodPost, "channels/*/invites"), perms: discord.PermissionCreateInstantInvite},
+	{route: newAPIRoute(http.MethodGet, "channels/*/webhooks"), perms: discord.PermissionManageWebhooks},
+	{route: newAPIRoute(http.MethodPost, "channels/*/webhooks"), perms: discord.PermissionManageWebhooks},
+	// guild endpoints
+	{route: newAPIRoute(http.MethodPatch, "guilds/*"), perms: discord.PermissionManageGuild},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/audit-logs"), perms: discord.PermissionViewAuditLog},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/bans"), perms: discord.PermissionBanMembers},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/bans/*"), perms: discord.PermissionBanMembers},
+	{route: newAPIRoute(http.MethodPut, "guilds/*/bans/*"), perms: discord.PermissionBanMembers},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/bans/*"), perms: discord.PermissionBanMembers},
+	{route: newAPIRoute(http.MethodPost, "guilds/*/channels"), perms: discord.PermissionManageChannels},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/channels"), perms: discord.PermissionManageChannels},
+	{route: newAPIRoute(http.MethodPost, "guilds/*/emojis"), perms: discord.PermissionManageEmojisAndStickers},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/emojis/*"), perms: discord.PermissionManageEmojisAndStickers},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/emojis/*"), perms: discord.PermissionManageEmojisAndStickers},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/integrations"), perms: discord.PermissionManageGuild},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/integrations/*"), perms: discord.PermissionManageGuild},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/invites"), perms: discord.PermissionManageGuild},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/members/*"), permFunc: modifyMemberPermissions},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/members/*"), perms: discord.PermissionKickMembers},
+	{route: newAPIRoute(http.MethodPut, "guilds/*/members/*/roles/*"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/members/*/roles/*"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/prune"), perms: discord.PermissionKickMembers},
+	{route: newAPIRoute(http.MethodPost, "guilds/*/prune"), perms: discord.PermissionKickMembers},
+	{route: newAPIRoute(http.MethodPost, "guilds/*/roles"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/roles"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/roles/*"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodDelete, "guilds/*/roles/*"), perms: discord.PermissionManageRoles},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/webhooks"), perms: discord.PermissionManageWebhooks},
+	{route: newAPIRoute(http.MethodGet, "guilds/*/widget"), perms: discord.PermissionManageGuild},
+	{route: newAPIRoute(http.MethodPatch, "guilds/*/widget"), perms: discord.PermissionManageGuild},
+}
+
+// memberFieldPermissions are the permissions required to modify the fields
+// of a member.
+var memberFieldPermissions = map[string]discord.Permissions{
+	"nick":                         discord.PermissionManageNicknames,
+	"roles":                        discord.PermissionManageRoles,
+	"mute":                         discord.PermissionMuteMembers,
+	"deaf":                         discord.PermissionDeafenMembers,
+	"channel_id":                   discord.PermissionMoveMembers,
+	"communication_disabled_until": discord.PermissionModerateMembers,
+}
+
+// WithPermissions makes the Mocker check the permissions of the bot on every
+// request to a mocked endpoint, based on the passed BotPermissions.
+//
+// If the bot lacks the permissions required by the endpoint, the request is
+// answered with Discord's 403 Missing Permissions error, or, if the bot
+// can't view the channel, with a 403 Missing Access.
+// The mock is still invoked, and will therefore check the request as usual.
+//
+// Requests to channels or guilds that are not included in the passed
+// BotPermissions are never answered with an error.
+func WithPermissions(p BotPermissions) Option {
+	return func(m *Mocker) {
+		s := &permissionState{
+			botID:          p.BotID,
+			guilds:         make(map[discord.GuildID]discord.Guild, len(p.Guilds)),
+			roles:          p.Roles,
+			channels:       make(map[discord.ChannelID]discord.Channel, len(p.Channels)),
+			messageAuthors: make(map[discord.MessageID]discord.UserID, len(p.Messages)),
+		}
+
+		for _, g := range p.Guilds {
+			s.guilds[g.ID] = g
+		}
+
+		for _, c := range p.Channels {
+			s.channels[c.ID] = c
+		}
+
+		for _, msg := range p.Messages {
+			s.messageAuthors[msg.ID] = msg.Author.ID
+		}
+
+		m.permissions = s
+	}
+}
+
+// permissionError checks if the bot has the permissions required by the
+// passed request, if the Mocker checks permissions.
+// If not, it returns the error to respond with.
+func (m *Mocker) permissionError(r *http.Request) *httputil.HTTPError {
+	if m.permissions == nil {
+		return nil
+	}
+
+	return m.permissions.permissionError(r)
+}
+
+// permissionError checks if the bot has the permissions required by the
+// passed request.
+// If not, it returns the error to respond with.
+func (s *permissionState) permissionError(r *http.Request) *httputil.HTTPError {
+	segments := apiSegments(r)
+	if len(segments) < 2 {
+		return nil
+	}
+
+	var (
+		have    discord.Permissions
+		channel bool
+	)
+
+	switch segments[0] {
+	case "channels":
+		id, err := discord.ParseSnowflake(segments[1])
+		if err != nil {
+			return nil
+		}
+
+		var ok bool
+		if have, ok = s.channelPermissions(discord.ChannelID(id)); !ok {
+			return nil
+		}
+
+		channel = true
+	case "guilds":
+		id, err := discord.ParseSnowflake(segments[1])
+		if err != nil {
+			return nil
+		}
+
+		var ok bool
+		if have, ok = s.guildPermissions(discord.GuildID(id)); !ok {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	if channel && !have.Has(discord.PermissionViewChannel) {
+		return &missingAccessError
+	}
+
+	for _, rule := range permissionRules {
+		if !rule.route.matches(r.Method, segments) {
+			continue
+		}
+
+		need := rule.perms
+		if rule.permFunc != nil {
+			need = rule.permFunc(s, r, segments)
+		}
+
+		if !have.Has(need) {
+			return &missingPermissionsError
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// guildPermissions returns the permissions the bot has in the guild with the
+// passed id.
+// If the guild is unknown, ok will be false.
+func (s *permissionState) guildPermissions(guildID discord.GuildID) (perms discord.Permissions, ok bool) {
+	g, ok := s.guilds[guildID]
+	if !ok {
+		return 0, false
+	}
+
+	return discord.CalcOverwrites(g, discord.Channel{}, s.member(guildID)), true
+}
+
+// channelPermissions returns the permissions the bot has in the channel with
+// the passed id.
+// If the channel or its guild is unknown, ok will be false.
+func (s *permissionState) channelPermissions(channelID discord.ChannelID) (perms discord.Permissions, ok bool) {
+	c, ok := s.channels[channelID]
+	if !ok {
+		return 0, false
+	}
+
+	g, ok := s.guilds[c.GuildID]
+	if !ok {
+		return 0, false
+	}
+
+	if isThread(c) {
+		if c, ok = s.channels[discord.ChannelID(c.ParentID)]; !ok {
+			return 0, false
+		}
+	}
+
+	return discord.CalcOverwrites(g, c, s.member(c.GuildID)), true
+}
+
+// member returns the member of the bot in the guild with the passed id.
+func (s *permissionState) member(guildID discord.GuildID) discord.Member {
+	return discord.Member{
+		User:    discord.User{ID: s.botID},
+		RoleIDs: s.roles[guildID],
+	}
+}
+
+// sentByBot checks if the message with the passed id is known to be sent by
+// the bot.
+func (s *permissionState) sentByBot(messageID string) bool {
+	id, err := discord.ParseSnowflake(messageID)
+	if err != nil {
+		return false
+	}
+
+	author, ok := s.messageAuthors[discord.MessageID(id)]
+	return ok && author == s.botID
+}
+
+func isThread(c discord.Channel) bool {
+	switch c.Type {
+	case discord.GuildNewsThread, discord.GuildPublicThread, discord.GuildPrivateThread:
+		return true
+	default:
+		return false
+	}
+}
+
+func sendMessagePermissions(s *permissionState, _ *http.Request, path []string) discord.Permissions {
+	id, _ := discord.ParseSnowflake(path[1])
+	if isThread(s.channels[discord.ChannelID(id)]) {
+		return discord.PermissionSendMessagesInThreads
+	}
+
+	return discord.PermissionSendMessages
+}
+
+func deleteMessagePermissions(s *permissionState, _ *http.Request, path []string) discord.Permissions {
+	if s.sentByBot(path[3]) {
+		return 0
+	}
+
+	return discord.PermissionManageMessages
+}
+
+func crosspostPermissions(s *permissionState, _ *http.Request, path []string) discord.Permissions {
+	if s.sentByBot(path[3]) {
+		return discord.PermissionSendMessages
+	}
+
+	return discord.PermissionManageMessages
+}
+
+func modifyMemberPermissions(s *permissionState, r *http.Request, path []string) discord.Permissions {
+	if r.Body == nil {
+		return 0
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return 0
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return 0
+	}
+
+	self := path[3] == "@me" || path[3] == s.botID.String()
+
+	var perms discord.Permissions
+
+	for name := range fields {
+		if name == "nick" && self {
+			perms |= discord.PermissionChangeNickname
+		} else {
+			perms |= memberFieldPermissions[name]
+		}
+	}
+
+	return perms
+}
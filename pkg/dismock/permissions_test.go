@@ -0,0 +1,201 @@
+package dismock
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPermissions(t *testing.T) {
+	const (
+		botID     discord.UserID    = 1
+		guildID   discord.GuildID   = 2
+		modRoleID discord.RoleID    = 3
+		channelID discord.ChannelID = 4
+		hiddenID  discord.ChannelID = 5
+		threadID  discord.ChannelID = 6
+		ownMsgID  discord.MessageID = 7
+		msgID     discord.MessageID = 8
+		userID    discord.UserID    = 9
+	)
+
+	perms := func(modPerms discord.Permissions) BotPermissions {
+		return BotPermissions{
+			BotID: botID,
+			Guilds: []discord.Guild{
+				{
+					ID:      guildID,
+					OwnerID: 123,
+					Roles: []discord.Role{
+						{
+							ID:          discord.RoleID(guildID),
+							Permissions: discord.PermissionViewChannel | discord.PermissionSendMessages,
+						},
+						{ID: modRoleID, Permissions: modPerms},
+					},
+				},
+			},
+			Roles: map[discord.GuildID][]discord.RoleID{guildID: {modRoleID}},
+			Channels: []discord.Channel{
+				{ID: channelID, GuildID: guildID},
+				{
+					ID:      hiddenID,
+					GuildID: guildID,
+					Overwrites: []discord.Overwrite{
+						{
+							ID:   discord.Snowflake(guildID),
+							Type: discord.OverwriteRole,
+							Deny: discord.PermissionViewChannel,
+						},
+					},
+				},
+				{
+					ID:       threadID,
+					GuildID:  guildID,
+					Type:     discord.GuildPublicThread,
+					ParentID: discord.ChannelID(channelID),
+				},
+			},
+			Messages: []discord.Message{
+				{ID: ownMsgID, ChannelID: channelID, Author: discord.User{ID: botID}},
+				{ID: msgID, ChannelID: channelID, Author: discord.User{ID: userID}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		modPerms  discord.Permissions
+		mock      func(m *Mocker)
+		request   func(c *api.Client) error
+		expectErr *httputil.HTTPError
+	}{
+		{
+			name: "delete own message",
+			mock: func(m *Mocker) { m.DeleteMessage(channelID, ownMsgID, "") },
+			request: func(c *api.Client) error {
+				return c.DeleteMessage(channelID, ownMsgID, "")
+			},
+		},
+		{
+			name: "delete message without manage messages",
+			mock: func(m *Mocker) { m.DeleteMessage(channelID, msgID, "") },
+			request: func(c *api.Client) error {
+				return c.DeleteMessage(channelID, msgID, "")
+			},
+			expectErr: &missingPermissionsError,
+		},
+		{
+			name:     "delete message with manage messages",
+			modPerms: discord.PermissionManageMessages,
+			mock:     func(m *Mocker) { m.DeleteMessage(channelID, msgID, "") },
+			request: func(c *api.Client) error {
+				return c.DeleteMessage(channelID, msgID, "")
+			},
+		},
+		{
+			name:     "delete message in hidden channel",
+			modPerms: discord.PermissionManageMessages,
+			mock:     func(m *Mocker) { m.DeleteMessage(hiddenID, msgID, "") },
+			request: func(c *api.Client) error {
+				return c.DeleteMessage(hiddenID, msgID, "")
+			},
+			expectErr: &missingAccessError,
+		},
+		{
+			name: "delete message in thread without manage messages",
+			mock: func(m *Mocker) { m.DeleteMessage(threadID, msgID, "") },
+			request: func(c *api.Client) error {
+				return c.DeleteMessage(threadID, msgID, "")
+			},
+			expectErr: &missingPermissionsError,
+		},
+		{
+			name: "ban without ban members",
+			mock: func(m *Mocker) { m.Ban(guildID, userID, api.BanData{}) },
+			request: func(c *api.Client) error {
+				return c.Ban(guildID, userID, api.BanData{})
+			},
+			expectErr: &missingPermissionsError,
+		},
+		{
+			name:     "ban with ban members",
+			modPerms: discord.PermissionBanMembers,
+			mock:     func(m *Mocker) { m.Ban(guildID, userID, api.BanData{}) },
+			request: func(c *api.Client) error {
+				return c.Ban(guildID, userID, api.BanData{})
+			},
+		},
+		{
+			name:     "ban with administrator",
+			modPerms: discord.PermissionAdministrator,
+			mock:     func(m *Mocker) { m.Ban(guildID, userID, api.BanData{}) },
+			request: func(c *api.Client) error {
+				return c.Ban(guildID, userID, api.BanData{})
+			},
+		},
+		{
+			name:     "modify member roles without manage roles",
+			modPerms: discord.PermissionManageNicknames,
+			mock: func(m *Mocker) {
+				m.ModifyMember(guildID, userID, api.ModifyMemberData{
+					Nick:  option.NewString("abc"),
+					Roles: &[]discord.RoleID{modRoleID},
+				})
+			},
+			request: func(c *api.Client) error {
+				return c.ModifyMember(guildID, userID, api.ModifyMemberData{
+					Nick:  option.NewString("abc"),
+					Roles: &[]discord.RoleID{modRoleID},
+				})
+			},
+			expectErr: &missingPermissionsError,
+		},
+		{
+			name:     "modify member nick with manage nicknames",
+			modPerms: discord.PermissionManageNicknames,
+			mock: func(m *Mocker) {
+				m.ModifyMember(guildID, userID, api.ModifyMemberData{Nick: option.NewString("abc")})
+			},
+			request: func(c *api.Client) error {
+				return c.ModifyMember(guildID, userID, api.ModifyMemberData{Nick: option.NewString("abc")})
+			},
+		},
+		{
+			name: "unknown guild",
+			mock: func(m *Mocker) { m.Ban(123, userID, api.BanData{}) },
+			request: func(c *api.Client) error {
+				return c.Ban(123, userID, api.BanData{})
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			m, s := NewSession(t, WithPermissions(perms(c.modPerms)))
+			c.mock(m)
+
+			err := c.request(s.Client)
+			if c.expectErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var httpErr *httputil.HTTPError
+			require.True(t, errors.As(err, &httpErr), "unexpected error: %v", err)
+
+			assert.Equal(t, http.StatusForbidden, httpErr.Status)
+			assert.Equal(t, c.expectErr.Code, httpErr.Code)
+			assert.Equal(t, c.expectErr.Message, httpErr.Message)
+		})
+	}
+}
@@ -0,0 +1,904 @@
+package dismock
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+	"github.com/diamondburned/arikawa/v3/voice/voicegateway"
+	"github.com/gorilla/websocket"
+
+	"github.com/mavolin/dismock/v3/internal/testing"
+)
+
+// VoiceConfig configures the mock voice server of a Mocker.
+type VoiceConfig struct {
+	// Timeout is the maximum duration to wait for clients, e.g. when
+	// waiting for a Speaking command, or when closing the voice server.
+	//
+	// It defaults to 5 seconds.
+	Timeout time.Duration
+	// HeartbeatInterval is the heartbeat interval sent in the Hello event.
+	//
+	// It defaults to 13.75 seconds, the interval used by Discord.
+	HeartbeatInterval time.Duration
+	// Token is the voice token sent in the Voice Server Update event.
+	// Clients must identify using this token.
+	//
+	// It defaults to "dismock".
+	Token string
+	// SSRC is the SSRC assigned to the first voice session in the Ready
+	// event.
+	// Every following voice session is assigned the SSRC following the one
+	// of the previous session.
+	//
+	// It defaults to 1.
+	SSRC uint32
+	// Modes are the encryption modes offered in the Ready event.
+	// Selecting a mode not in Modes closes the connection with close code
+	// 4016.
+	//
+	// It defaults to all modes supported by the voice server, i.e.
+	// xsalsa20_poly1305, xsalsa20_poly1305_suffix, xsalsa20_poly1305_lite,
+	// aead_aes256_gcm_rtpsize and aead_xchacha20_poly1305_rtpsize.
+	Modes []string
+	// SecretKey is the secret key sent in the Session Description event.
+	//
+	// If it is empty, a random key is used.
+	SecretKey [32]byte
+	// TrustViaSSLCertFile makes clients, that don't allow configuring the
+	// root certificates they trust, such as arikawa's voice client, trust
+	// the voice server, by pointing SSL_CERT_FILE to its certificate for the
+	// duration of the test.
+	// See VoiceGateway for the caveats of doing so.
+	//
+	// If it is false, clients must be configured to trust the voice server
+	// using VoiceGateway.TLSConfig.
+	TrustViaSSLCertFile bool
+}
+
+// VoiceGateway is a mock of Discord's voice servers.
+//
+// It consists of a voice websocket, which performs the Hello, Identify,
+// Ready, Select Protocol, Session Description handshake with connecting
+// clients, and a UDP server, which answers IP discovery requests and
+// captures the RTP packets sent by clients.
+// Captured packets are decrypted using the encryption mode selected by the
+// client, and can be accessed using Packets and WaitForPackets.
+// Audio of other users can be sent to clients using SendAudio.
+//
+// Since arikawa's voice client always connects using TLS, the websocket is
+// served using TLS as well.
+// Clients that allow configuring TLS can trust the voice server using
+// TLSConfig.
+// Because arikawa's voice client doesn't, it can only connect, if
+// VoiceConfig.TrustViaSSLCertFile is set.
+// SSL_CERT_FILE is then pointed to a temporary file containing the
+// certificate of the voice server using testing.T.Setenv, i.e. until the
+// test finishes, and the test may therefore not be parallel.
+//
+// Go loads the system's root certificates only once per process.
+// Hence, this only works if no certificate was verified using them before,
+// and, if a certificate is verified while SSL_CERT_FILE is set, the
+// certificate of the voice server stays trusted for the rest of the process.
+// It also only works on systems, on which Go respects SSL_CERT_FILE, i.e.
+// not on macOS, iOS and Windows.
+//
+// If a client rejects the certificate of the voice server, the test fails.
+type VoiceGateway struct {
+	// Server is the httptest.Server serving the voice websocket.
+	Server *httptest.Server
+	// UDP is the connection of the voice UDP server.
+	UDP *net.UDPConn
+
+	config  VoiceConfig
+	t       testing.TInterface
+	gateway *Gateway
+
+	// wg is used to wait for all connections and the UDP server to be
+	// closed.
+	wg sync.WaitGroup
+
+	mut *sync.Mutex
+	// closed indicates whether the VoiceGateway was closed.
+	closed bool
+	// conns are the currently open websocket connections.
+	conns map[*voiceConn]struct{}
+	// joins are the voice channels the client joined using Update Voice
+	// State commands, sorted by guild id.
+	joins map[discord.GuildID]voiceJoin
+	// sessions are the voice sessions, sorted by guild id.
+	sessions map[discord.GuildID]*voiceSession
+	// heartbeats is the number of heartbeats received.
+	heartbeats int
+	// nextSSRC is the SSRC assigned to the next voice session.
+	nextSSRC uint32
+
+	// changed is closed and replaced, every time the state of the
+	// VoiceGateway changes.
+	changed chan struct{}
+}
+
+type (
+	// voiceJoin is a voice channel joined by the client.
+	voiceJoin struct {
+		channelID discord.ChannelID
+		// sessionID is the id of the gateway session that joined the
+		// channel.
+		sessionID string
+	}
+
+	// voiceSession is a voice session created through an Identify.
+	voiceSession struct {
+		guildID   discord.GuildID
+		sessionID string
+		ssrc      uint32
+
+		// mode is the encryption mode selected by the client.
+		// It is empty, if the client hasn't selected a protocol yet.
+		mode string
+		// speaking is the speaking flag most recently sent by the client.
+		speaking voicegateway.SpeakingFlag
+		// packets are the RTP packets sent by the client.
+		packets []VoicePacket
+
+		// addr is the address of the client's UDP connection.
+		// It is nil, if the client hasn't performed IP discovery yet.
+		addr *net.UDPAddr
+		// streams are the RTP streams sent to the client, sorted by ssrc.
+		streams map[uint32]*rtpStream
+		// nonce is the nonce of the last RTP packet sent to the client, for
+		// encryption modes using an incrementing nonce.
+		nonce uint32
+
+		// conn is the connection of the session.
+		// It is nil, if the client disconnected.
+		conn *voiceConn
+	}
+
+	// voiceConn is a connection to the voice websocket.
+	voiceConn struct {
+		v  *VoiceGateway
+		ws *websocket.Conn
+
+		// writeMut is locked while writing to ws.
+		writeMut sync.Mutex
+
+		// session is the session the connection identified or resumed
+		// with.
+		// It is nil, if the client hasn't identified yet.
+		session *voiceSession
+	}
+)
+
+// Voice gateway op codes.
+const (
+	voiceIdentifyOp           ws.OpCode = 0
+	voiceSelectProtocolOp     ws.OpCode = 1
+	voiceReadyOp              ws.OpCode = 2
+	voiceHeartbeatOp          ws.OpCode = 3
+	voiceSessionDescriptionOp ws.OpCode = 4
+	voiceSpeakingOp           ws.OpCode = 5
+	voiceHeartbeatAckOp       ws.OpCode = 6
+	voiceResumeOp             ws.OpCode = 7
+	voiceHelloOp              ws.OpCode = 8
+	voiceResumedOp            ws.OpCode = 9
+	voiceClientConnectOp      ws.OpCode = 12
+	voiceClientDisconnectOp   ws.OpCode = 13
+)
+
+const (
+	// defaultVoiceHeartbeat is the heartbeat interval used by Discord's
+	// voice servers.
+	defaultVoiceHeartbeat = 13750 * time.Millisecond
+	// defaultVoiceToken is the default value for VoiceConfig.Token.
+	defaultVoiceToken = "dismock"
+)
+
+// Voice gateway close codes.
+const (
+	voiceCloseUnknownOpcode       = 4001
+	voiceCloseDecodeError         = 4002
+	voiceCloseNotAuthenticated    = 4003
+	voiceCloseAuthenticationError = 4004
+	voiceCloseAlreadyAuthed       = 4005
+	voiceCloseInvalidSession      = 4006
+	voiceCloseServerNotFound      = 4011
+	voiceCloseUnknownProtocol     = 4012
+	voiceCloseDisconnected        = 4014
+	voiceCloseUnknownMode         = 4016
+)
+
+// voiceCloseReasons are the reasons Discord sends with its voice close
+// codes.
+var voiceCloseReasons = map[int]string{
+	voiceCloseUnknownOpcode:       "Unknown opcode.",
+	voiceCloseDecodeError:         "Failed to decode payload.",
+	voiceCloseNotAuthenticated:    "Not authenticated.",
+	voiceCloseAuthenticationError: "Authentication failed.",
+	voiceCloseAlreadyAuthed:       "Already authenticated.",
+	voiceCloseInvalidSession:      "Session no longer valid.",
+	voiceCloseServerNotFound:      "Server not found.",
+	voiceCloseUnknownProtocol:     "Unknown protocol.",
+	voiceCloseDisconnected:        "Disconnected.",
+	voiceCloseUnknownMode:         "Unknown encryption mode.",
+}
+
+// voiceModes are the encryption modes supported by the VoiceGateway.
+var voiceModes = []string{
+	"xsalsa20_poly1305",
+	"xsalsa20_poly1305_suffix",
+	"xsalsa20_poly1305_lite",
+	"aead_aes256_gcm_rtpsize",
+	"aead_xchacha20_poly1305_rtpsize",
+}
+
+// WithVoice starts a mock voice server for the Mocker, that can be accessed
+// through Mocker.Voice.
+//
+// WithVoice requires the WithGateway option.
+// Once the Mocker has a voice server, Update Voice State commands expected
+// using Gateway.UpdateVoiceState are answered with a Voice State Update and
+// a Voice Server Update event, pointing the client to the voice server.
+func WithVoice(c VoiceConfig) Option {
+	if c.Timeout == 0 {
+		c.Timeout = defaultGatewayTimeout
+	}
+
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = defaultVoiceHeartbeat
+	}
+
+	if c.Token == "" {
+		c.Token = defaultVoiceToken
+	}
+
+	if c.SSRC == 0 {
+		c.SSRC = 1
+	}
+
+	if c.Modes == nil {
+		c.Modes = voiceModes
+	}
+
+	if c.SecretKey == [32]byte{} {
+		_, _ = rand.Read(c.SecretKey[:])
+	}
+
+	return func(m *Mocker) {
+		m.Voice = &VoiceGateway{
+			config:   c,
+			mut:      new(sync.Mutex),
+			conns:    make(map[*voiceConn]struct{}),
+			joins:    make(map[discord.GuildID]voiceJoin),
+			sessions: make(map[discord.GuildID]*voiceSession),
+			nextSSRC: c.SSRC,
+			changed:  make(chan struct{}),
+		}
+	}
+}
+
+// start starts the websocket and UDP server of the VoiceGateway.
+func (v *VoiceGateway) start(m *Mocker) {
+	v.t = m.t
+
+	if m.Gateway == nil {
+		v.t.Fatal("dismock: WithVoice requires the WithGateway option")
+		return
+	}
+
+	v.gateway = m.Gateway
+	m.Gateway.voice = v
+
+	v.Server = httptest.NewUnstartedServer(http.HandlerFunc(v.serve))
+	v.Server.Config.ErrorLog = log.New(voiceErrorLog{v}, "", 0)
+	v.Server.StartTLS()
+
+	if v.config.TrustViaSSLCertFile {
+		v.trust()
+	}
+
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		v.t.Fatal("dismock: failed to start voice UDP server:", err)
+		return
+	}
+
+	v.UDP = udp
+
+	v.wg.Add(1)
+	go v.serveUDP()
+}
+
+// sslCertFileSupported specifies whether Go respects SSL_CERT_FILE on the
+// current platform.
+// On macOS, iOS and Windows, certificates are verified using the platform's
+// APIs instead.
+func sslCertFileSupported() bool {
+	switch runtime.GOOS {
+	case "darwin", "ios", "windows":
+		return false
+	default:
+		return true
+	}
+}
+
+// trust makes clients trust the certificate of the voice server, by
+// pointing SSL_CERT_FILE to it until the test finishes.
+func (v *VoiceGateway) trust() {
+	if !sslCertFileSupported() {
+		v.t.Fatal("dismock: VoiceConfig.TrustViaSSLCertFile is not supported on " + runtime.GOOS)
+		return
+	}
+
+	f, err := ioutil.TempFile("", "dismock-voice-*.pem")
+	if err != nil {
+		v.t.Fatal("dismock: failed to create voice server certificate file:", err)
+		return
+	}
+
+	v.t.Cleanup(func() { _ = os.Remove(f.Name()) })
+
+	err = pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: v.Server.Certificate().Raw})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		v.t.Fatal("dismock: failed to write voice server certificate:", err)
+		return
+	}
+
+	v.t.Setenv("SSL_CERT_FILE", f.Name())
+}
+
+// voiceErrorLog is the io.Writer of the log.Logger used by the
+// http.Server of a VoiceGateway.
+// It fails the test, if a client rejected the certificate of the voice
+// server, and writes all other errors to the standard logger.
+type voiceErrorLog struct {
+	v *VoiceGateway
+}
+
+func (l voiceErrorLog) Write(p []byte) (int, error) {
+	msg := string(p)
+
+	if strings.Contains(msg, "TLS handshake error") &&
+		(strings.Contains(msg, "bad certificate") || strings.Contains(msg, "unknown certificate authority")) {
+		if l.v.config.TrustViaSSLCertFile {
+			l.v.t.Errorf("dismock: a client rejected the certificate of the voice server, although "+
+				"VoiceConfig.TrustViaSSLCertFile is set, probably because the system's root certificates were "+
+				"already loaded before: %s", strings.TrimSpace(msg))
+		} else {
+			l.v.t.Errorf("dismock: a client rejected the certificate of the voice server: set "+
+				"VoiceConfig.TrustViaSSLCertFile, or configure the client using VoiceGateway.TLSConfig: %s",
+				strings.TrimSpace(msg))
+		}
+
+		return len(p), nil
+	}
+
+	return log.Writer().Write(p)
+}
+
+// TLSConfig returns a tls.Config trusting the certificate of the voice
+// server.
+// It can be used to connect clients to the voice server, that allow
+// configuring TLS, without VoiceConfig.TrustViaSSLCertFile.
+func (v *VoiceGateway) TLSConfig() *tls.Config {
+	return v.Server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+}
+
+// Endpoint returns the endpoint of the voice server, as sent in Voice Server
+// Update events.
+func (v *VoiceGateway) Endpoint() string {
+	return v.Server.Listener.Addr().String()
+}
+
+// close closes all connections to the VoiceGateway and shuts down its
+// servers.
+func (v *VoiceGateway) close() {
+	if v.Server == nil { // the VoiceGateway was never started
+		return
+	}
+
+	v.Server.Close()
+
+	v.mut.Lock()
+	v.closed = true
+	conns := make([]*voiceConn, 0, len(v.conns))
+	for c := range v.conns {
+		conns = append(conns, c)
+	}
+	v.mut.Unlock()
+
+	for _, c := range conns {
+		_ = c.ws.Close()
+	}
+
+	if v.UDP != nil {
+		_ = v.UDP.Close()
+	}
+
+	v.wg.Wait()
+}
+
+// joinVoice answers the passed Update Voice State command with a Voice State
+// Update and, if the client joined a channel, a Voice Server Update event.
+// If the client left the channel, the voice connection of the guild is
+// closed.
+func (c *gatewayConn) joinVoice(cmd gateway.UpdateVoiceStateCommand) {
+	g := c.g
+	v := g.voice
+
+	g.mut.Lock()
+	s := c.session
+	g.mut.Unlock()
+
+	if s == nil {
+		return
+	}
+
+	v.mut.Lock()
+
+	if cmd.ChannelID.IsValid() {
+		v.joins[cmd.GuildID] = voiceJoin{channelID: cmd.ChannelID, sessionID: s.id}
+	} else {
+		delete(v.joins, cmd.GuildID)
+	}
+
+	var conn *voiceConn
+	if vs := v.sessions[cmd.GuildID]; vs != nil && !cmd.ChannelID.IsValid() {
+		delete(v.sessions, cmd.GuildID)
+		conn = vs.conn
+	}
+
+	v.notify()
+	v.mut.Unlock()
+
+	if conn != nil {
+		conn.close(voiceCloseDisconnected)
+	}
+
+	err := g.dispatch(s, &gateway.VoiceStateUpdateEvent{VoiceState: discord.VoiceState{
+		GuildID:   cmd.GuildID,
+		ChannelID: cmd.ChannelID,
+		UserID:    g.config.User.ID,
+		SessionID: s.id,
+		SelfMute:  cmd.SelfMute,
+		SelfDeaf:  cmd.SelfDeaf,
+	}})
+	if err != nil || !cmd.ChannelID.IsValid() {
+		return
+	}
+
+	_ = g.dispatch(s, &gateway.VoiceServerUpdateEvent{
+		Token:    v.config.Token,
+		GuildID:  cmd.GuildID,
+		Endpoint: v.Endpoint(),
+	})
+}
+
+// serve serves a websocket connection to the VoiceGateway.
+func (v *VoiceGateway) serve(w http.ResponseWriter, r *http.Request) {
+	v.wg.Add(1)
+	defer v.wg.Done()
+
+	wsConn, err := gatewayUpgrader.Upgrade(w, r, nil)
+	if err != nil { // Upgrade already responded with an error
+		return
+	}
+
+	c := &voiceConn{v: v, ws: wsConn}
+
+	v.mut.Lock()
+	if v.closed {
+		v.mut.Unlock()
+		_ = wsConn.Close()
+		return
+	}
+	v.conns[c] = struct{}{}
+	v.mut.Unlock()
+
+	defer func() {
+		v.mut.Lock()
+		delete(v.conns, c)
+		if c.session != nil && c.session.conn == c {
+			c.session.conn = nil
+		}
+		v.notify()
+		v.mut.Unlock()
+
+		_ = wsConn.Close()
+	}()
+
+	err = c.write(voiceHelloOp, voicegateway.HelloEvent{
+		HeartbeatInterval: discord.Milliseconds(v.config.HeartbeatInterval / time.Millisecond),
+	})
+	if err != nil {
+		return
+	}
+
+	for {
+		_, data, err := wsConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var p gatewayPayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			c.close(voiceCloseDecodeError)
+			return
+		}
+
+		if !c.handle(p) {
+			return
+		}
+	}
+}
+
+// handle handles the passed payload sent by the client.
+// It returns false, if the connection was closed.
+func (c *voiceConn) handle(p gatewayPayload) bool {
+	switch p.Op {
+	case voiceHeartbeatOp:
+		c.v.mut.Lock()
+		c.v.heartbeats++
+		c.v.notify()
+		c.v.mut.Unlock()
+
+		// the nonce is echoed as is, so that large nonces don't lose
+		// precision
+		return c.writePayload(gatewayPayload{Op: voiceHeartbeatAckOp, Data: p.Data}) == nil
+	case voiceIdentifyOp:
+		if c.authenticated() {
+			c.close(voiceCloseAlreadyAuthed)
+			return false
+		}
+
+		var identify voicegateway.IdentifyCommand
+		if err := json.Unmarshal(p.Data, &identify); err != nil {
+			c.close(voiceCloseDecodeError)
+			return false
+		}
+
+		return c.identify(identify)
+	case voiceResumeOp:
+		if c.authenticated() {
+			c.close(voiceCloseAlreadyAuthed)
+			return false
+		}
+
+		var resume voicegateway.ResumeCommand
+		if err := json.Unmarshal(p.Data, &resume); err != nil {
+			c.close(voiceCloseDecodeError)
+			return false
+		}
+
+		return c.resume(resume)
+	case voiceSelectProtocolOp:
+		if !c.authenticated() {
+			c.close(voiceCloseNotAuthenticated)
+			return false
+		}
+
+		var sp voicegateway.SelectProtocolCommand
+		if err := json.Unmarshal(p.Data, &sp); err != nil {
+			c.close(voiceCloseDecodeError)
+			return false
+		}
+
+		return c.selectProtocol(sp)
+	case voiceSpeakingOp:
+		if !c.authenticated() {
+			c.close(voiceCloseNotAuthenticated)
+			return false
+		}
+
+		var speaking voicegateway.SpeakingEvent
+		if err := json.Unmarshal(p.Data, &speaking); err != nil {
+			c.close(voiceCloseDecodeError)
+			return false
+		}
+
+		c.speaking(speaking)
+		return true
+	default:
+		if !c.authenticated() {
+			c.close(voiceCloseNotAuthenticated)
+			return false
+		}
+
+		c.close(voiceCloseUnknownOpcode)
+		return false
+	}
+}
+
+// authenticated returns whether the connection belongs to a session.
+func (c *voiceConn) authenticated() bool {
+	c.v.mut.Lock()
+	defer c.v.mut.Unlock()
+
+	return c.session != nil
+}
+
+// identify creates a new voice session using the passed Identify command
+// and sends the Ready event.
+// It returns false, if the connection was closed.
+func (c *voiceConn) identify(identify voicegateway.IdentifyCommand) bool {
+	v := c.v
+
+	if identify.Token != v.config.Token {
+		v.t.Errorf("dismock: client identified with voice token %q, but expected %q",
+			identify.Token, v.config.Token)
+		c.close(voiceCloseAuthenticationError)
+		return false
+	}
+
+	if userID := v.gateway.config.User.ID; userID.IsValid() && identify.UserID != userID {
+		v.t.Errorf("dismock: client identified with user id %d, but expected %d", identify.UserID, userID)
+		c.close(voiceCloseAuthenticationError)
+		return false
+	}
+
+	v.mut.Lock()
+
+	join, ok := v.joins[identify.GuildID]
+	if !ok {
+		v.mut.Unlock()
+		v.t.Errorf("dismock: client identified with guild %d, but didn't join a voice channel in it",
+			identify.GuildID)
+		c.close(voiceCloseServerNotFound)
+		return false
+	}
+
+	if identify.SessionID != join.sessionID {
+		v.mut.Unlock()
+		v.t.Errorf("dismock: client identified with session id %q, but joined the voice channel with %q",
+			identify.SessionID, join.sessionID)
+		c.close(voiceCloseInvalidSession)
+		return false
+	}
+
+	s := &voiceSession{
+		guildID:   identify.GuildID,
+		sessionID: identify.SessionID,
+		ssrc:      v.nextSSRC,
+		streams:   make(map[uint32]*rtpStream),
+		conn:      c,
+	}
+
+	v.nextSSRC++
+
+	prev := v.sessions[identify.GuildID]
+	v.sessions[identify.GuildID] = s
+	c.session = s
+	v.notify()
+
+	v.mut.Unlock()
+
+	if prev != nil && prev.conn != nil && prev.conn != c {
+		prev.conn.close(voiceCloseInvalidSession)
+	}
+
+	addr := v.UDP.LocalAddr().(*net.UDPAddr)
+
+	return c.write(voiceReadyOp, voicegateway.ReadyEvent{
+		SSRC:        s.ssrc,
+		IP:          addr.IP.String(),
+		Port:        addr.Port,
+		Modes:       v.config.Modes,
+		Experiments: []string{},
+	}) == nil
+}
+
+// resume resumes the voice session referenced by the passed Resume command.
+// It returns false, if the connection was closed.
+func (c *voiceConn) resume(resume voicegateway.ResumeCommand) bool {
+	v := c.v
+
+	if resume.Token != v.config.Token {
+		v.t.Errorf("dismock: client resumed with voice token %q, but expected %q", resume.Token, v.config.Token)
+		c.close(voiceCloseAuthenticationError)
+		return false
+	}
+
+	v.mut.Lock()
+
+	s := v.sessions[resume.GuildID]
+	if s == nil || s.sessionID != resume.SessionID {
+		v.mut.Unlock()
+		v.t.Errorf("dismock: client resumed unknown voice session %q in guild %d", resume.SessionID, resume.GuildID)
+		c.close(voiceCloseInvalidSession)
+		return false
+	}
+
+	prev := s.conn
+	s.conn = c
+	c.session = s
+	v.notify()
+
+	v.mut.Unlock()
+
+	if prev != nil && prev != c {
+		prev.close(voiceCloseInvalidSession)
+	}
+
+	return c.write(voiceResumedOp, nil) == nil
+}
+
+// selectProtocol selects the encryption mode of the passed Select Protocol
+// command and sends the Session Description event.
+// It returns false, if the connection was closed.
+func (c *voiceConn) selectProtocol(sp voicegateway.SelectProtocolCommand) bool {
+	v := c.v
+
+	if sp.Protocol != "udp" {
+		v.t.Errorf("dismock: client selected unknown protocol %q", sp.Protocol)
+		c.close(voiceCloseUnknownProtocol)
+		return false
+	}
+
+	var supported bool
+	for _, mode := range v.config.Modes {
+		if sp.Data.Mode == mode {
+			supported = true
+			break
+		}
+	}
+
+	if !supported {
+		v.t.Errorf("dismock: client selected encryption mode %q, but only %v are available",
+			sp.Data.Mode, v.config.Modes)
+		c.close(voiceCloseUnknownMode)
+		return false
+	}
+
+	v.mut.Lock()
+	c.session.mode = sp.Data.Mode
+	v.notify()
+	v.mut.Unlock()
+
+	return c.write(voiceSessionDescriptionOp, voicegateway.SessionDescriptionEvent{
+		Mode:      sp.Data.Mode,
+		SecretKey: v.config.SecretKey,
+	}) == nil
+}
+
+// speaking updates the speaking flag of the session of the connection.
+func (c *voiceConn) speaking(speaking voicegateway.SpeakingEvent) {
+	v := c.v
+
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	if speaking.SSRC != c.session.ssrc {
+		v.t.Errorf("dismock: client sent speaking with ssrc %d, but was assigned ssrc %d",
+			speaking.SSRC, c.session.ssrc)
+	}
+
+	c.session.speaking = speaking.Speaking
+	v.notify()
+}
+
+// Mode returns the encryption mode the client selected for its voice
+// connection in the guild with the passed id.
+// If the client hasn't selected a mode yet, an empty string is returned.
+func (v *VoiceGateway) Mode(guildID discord.GuildID) string {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	if s := v.sessions[guildID]; s != nil {
+		return s.mode
+	}
+
+	return ""
+}
+
+// Speaking returns the speaking flag the client most recently sent for its
+// voice connection in the guild with the passed id.
+func (v *VoiceGateway) Speaking(guildID discord.GuildID) voicegateway.SpeakingFlag {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	if s := v.sessions[guildID]; s != nil {
+		return s.speaking
+	}
+
+	return voicegateway.NotSpeaking
+}
+
+// WaitForSpeaking waits until the client sends the passed speaking flag for
+// its voice connection in the guild with the passed id, or the Timeout of
+// the VoiceConfig passes, in which case the test fails.
+func (v *VoiceGateway) WaitForSpeaking(guildID discord.GuildID, flag voicegateway.SpeakingFlag) {
+	ok := v.waitFor(func() bool {
+		s := v.sessions[guildID]
+		return s != nil && s.speaking == flag
+	})
+	if !ok {
+		v.t.Errorf("dismock: timed out waiting for speaking flag %d in guild %d", flag, guildID)
+	}
+}
+
+// waitFor waits until cond returns true, or the Timeout of the VoiceConfig
+// passes.
+// cond is called with mut locked.
+func (v *VoiceGateway) waitFor(cond func() bool) bool {
+	timer := time.NewTimer(v.config.Timeout)
+	defer timer.Stop()
+
+	for {
+		v.mut.Lock()
+		done, changed := cond(), v.changed
+		v.mut.Unlock()
+
+		if done {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// notify notifies all waiting calls to waitFor, that the state of the
+// VoiceGateway changed.
+// mut must be locked.
+func (v *VoiceGateway) notify() {
+	close(v.changed)
+	v.changed = make(chan struct{})
+}
+
+// write writes a payload with the passed op code and data to the client.
+func (c *voiceConn) write(op ws.OpCode, data interface{}) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		c.v.t.Errorf("dismock: failed to encode voice gateway payload: %s", err)
+		return err
+	}
+
+	return c.writePayload(gatewayPayload{Op: op, Data: rawData})
+}
+
+// writePayload writes the passed payload to the client.
+func (c *voiceConn) writePayload(p gatewayPayload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		c.v.t.Errorf("dismock: failed to encode voice gateway payload: %s", err)
+		return err
+	}
+
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+
+	return c.ws.WriteMessage(websocket.TextMessage, payload)
+}
+
+// close closes the connection using the passed close code, and the reason
+// Discord sends with it.
+func (c *voiceConn) close(code int) {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+
+	_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, voiceCloseReasons[code]),
+		time.Now().Add(time.Second))
+	_ = c.ws.Close()
+}
@@ -0,0 +1,147 @@
+package dismock
+
+import (
+	"encoding/binary"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+	"github.com/diamondburned/arikawa/v3/voice/voicegateway"
+)
+
+const (
+	// rtpPayloadType is the payload type used by Discord for opus.
+	rtpPayloadType = 0x78
+	// rtpTimestampIncrement is the increment of the RTP timestamp between two
+	// 20ms opus frames sampled at 48kHz.
+	rtpTimestampIncrement = 960
+)
+
+// rtpStream is an RTP stream sent to the client.
+type rtpStream struct {
+	// userID is the id of the user the stream belongs to.
+	userID    discord.UserID
+	sequence  uint16
+	timestamp uint32
+}
+
+// ClientConnect sends a Client Connect event to the client connected to the
+// voice channel in the guild with the passed id, announcing that the user
+// with the passed id joined the channel using the passed audio ssrc.
+func (v *VoiceGateway) ClientConnect(guildID discord.GuildID, userID discord.UserID, ssrc uint32) {
+	v.writeEvent(guildID, voiceClientConnectOp, voicegateway.ClientConnectEvent{UserID: userID, AudioSSRC: ssrc})
+}
+
+// ClientDisconnect sends a Client Disconnect event to the client connected
+// to the voice channel in the guild with the passed id, announcing that the
+// user with the passed id left the channel.
+//
+// Subsequent calls to SendAudio for the user start new RTP streams, and are
+// therefore preceded by a Speaking event again.
+func (v *VoiceGateway) ClientDisconnect(guildID discord.GuildID, userID discord.UserID) {
+	v.mut.Lock()
+	if s := v.sessions[guildID]; s != nil {
+		for ssrc, stream := range s.streams {
+			if stream.userID == userID {
+				delete(s.streams, ssrc)
+			}
+		}
+	}
+	v.mut.Unlock()
+
+	v.writeEvent(guildID, voiceClientDisconnectOp, voicegateway.ClientDisconnectEvent{UserID: userID})
+}
+
+// SendAudio sends the passed opus frames as the user with the passed id and
+// audio ssrc to the client connected to the voice channel in the guild with
+// the passed id.
+//
+// If this is the first time audio is sent using the passed ssrc, a Speaking
+// event announcing that the user started speaking using it is sent first.
+// Afterwards, each frame is sent as an RTP packet encrypted using the
+// encryption mode selected by the client.
+// Frames are expected to be 20ms long: The sequence number of each packet is
+// incremented by one and its timestamp by 960, continuing where the
+// previous call to SendAudio for the same ssrc left off.
+//
+// The frames are sent immediately, without waiting 20ms between frames.
+func (v *VoiceGateway) SendAudio(guildID discord.GuildID, userID discord.UserID, ssrc uint32, frames ...[]byte) {
+	v.mut.Lock()
+
+	s := v.sessions[guildID]
+	if s == nil || s.conn == nil {
+		v.mut.Unlock()
+		v.t.Errorf("dismock: no client is connected to the voice channel in guild %d", guildID)
+		return
+	} else if s.mode == "" || s.addr == nil {
+		v.mut.Unlock()
+		v.t.Errorf("dismock: cannot send audio to the voice connection in guild %d before the client selected "+
+			"a protocol", guildID)
+		return
+	}
+
+	stream := s.streams[ssrc]
+	if stream == nil {
+		stream = &rtpStream{userID: userID}
+		s.streams[ssrc] = stream
+
+		v.mut.Unlock()
+
+		v.writeEvent(guildID, voiceSpeakingOp, voicegateway.SpeakingEvent{
+			Speaking: voicegateway.Microphone,
+			SSRC:     ssrc,
+			UserID:   userID,
+		})
+
+		v.mut.Lock()
+	}
+
+	defer v.mut.Unlock()
+
+	for _, f := range frames {
+		header := make([]byte, rtpHeaderSize, rtpHeaderSize+len(f)+64)
+		header[0] = 0x80
+		header[1] = rtpPayloadType
+		binary.BigEndian.PutUint16(header[2:4], stream.sequence)
+		binary.BigEndian.PutUint32(header[4:8], stream.timestamp)
+		binary.BigEndian.PutUint32(header[8:12], ssrc)
+
+		s.nonce++
+
+		packet, err := sealRTP(s.mode, &v.config.SecretKey, header, f, s.nonce)
+		if err != nil {
+			v.t.Errorf("dismock: failed to encrypt RTP packet using %s: %s", s.mode, err)
+			return
+		}
+
+		if _, err := v.UDP.WriteToUDP(packet, s.addr); err != nil {
+			v.t.Errorf("dismock: failed to send RTP packet: %s", err)
+			return
+		}
+
+		stream.sequence++
+		stream.timestamp += rtpTimestampIncrement
+	}
+}
+
+// writeEvent writes a payload with the passed op code and data to the
+// client connected to the voice channel in the guild with the passed id.
+// If there is no such client, the test fails.
+func (v *VoiceGateway) writeEvent(guildID discord.GuildID, op ws.OpCode, data interface{}) {
+	v.mut.Lock()
+
+	var c *voiceConn
+	if s := v.sessions[guildID]; s != nil {
+		c = s.conn
+	}
+
+	v.mut.Unlock()
+
+	if c == nil {
+		v.t.Errorf("dismock: no client is connected to the voice channel in guild %d", guildID)
+		return
+	}
+
+	if err := c.write(op, data); err != nil {
+		v.t.Errorf("dismock: failed to send voice gateway event with op %d: %s", op, err)
+	}
+}
@@ -0,0 +1,99 @@
+package dismock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/voice/voicegateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoiceGateway_SendAudio(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s, vs := newVoiceSession(t, VoiceConfig{})
+
+		events := make(chan interface{}, 4)
+		vs.AddHandler(func(e *voicegateway.ClientConnectEvent) { events <- e })
+		vs.AddHandler(func(e *voicegateway.SpeakingEvent) { events <- e })
+		vs.AddHandler(func(e *voicegateway.ClientDisconnectEvent) { events <- e })
+
+		joinVoice(t, m, vs, 123, 456)
+
+		m.Voice.ClientConnect(123, 2, 100)
+		assert.Equal(t, &voicegateway.ClientConnectEvent{UserID: 2, AudioSSRC: 100}, receiveEvent(t, events))
+
+		m.Voice.SendAudio(123, 2, 100, []byte{1, 2, 3}, []byte{4, 5})
+		m.Voice.SendAudio(123, 3, 101, []byte{6})
+		m.Voice.SendAudio(123, 2, 100, []byte{7})
+
+		// arikawa calls handlers asynchronously, so the order of events is
+		// not guaranteed
+		assert.ElementsMatch(t, []interface{}{
+			&voicegateway.SpeakingEvent{Speaking: voicegateway.Microphone, SSRC: 100, UserID: 2},
+			&voicegateway.SpeakingEvent{Speaking: voicegateway.Microphone, SSRC: 101, UserID: 3},
+		}, []interface{}{receiveEvent(t, events), receiveEvent(t, events)})
+
+		expect := []VoicePacket{
+			{SSRC: 100, Sequence: 0, Timestamp: 0, Opus: []byte{1, 2, 3}},
+			{SSRC: 100, Sequence: 1, Timestamp: 960, Opus: []byte{4, 5}},
+			{SSRC: 101, Sequence: 0, Timestamp: 0, Opus: []byte{6}},
+			{SSRC: 100, Sequence: 2, Timestamp: 1920, Opus: []byte{7}},
+		}
+
+		for _, e := range expect {
+			p, err := vs.ReadPacket()
+			require.NoError(t, err)
+
+			assert.Equal(t, e, VoicePacket{
+				SSRC:      p.SSRC(),
+				Sequence:  p.Sequence(),
+				Timestamp: p.Timestamp(),
+				Opus:      append([]byte(nil), p.Opus...),
+			})
+		}
+
+		m.Voice.ClientDisconnect(123, 2)
+		assert.Equal(t, &voicegateway.ClientDisconnectEvent{UserID: 2}, receiveEvent(t, events))
+
+		m.Gateway.UpdateVoiceState(gateway.UpdateVoiceStateCommand{
+			GuildID:   123,
+			ChannelID: discord.ChannelID(discord.NullSnowflake),
+			SelfMute:  true,
+			SelfDeaf:  true,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, vs.Leave(ctx))
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("not connected", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}), WithVoice(VoiceConfig{}))
+		defer m.Close()
+
+		m.Voice.SendAudio(123, 2, 100, []byte{1, 2, 3})
+		assert.True(t, tMock.Failed())
+	})
+}
+
+// receiveEvent receives an event from the passed channel, failing the test
+// if none is received within 5 seconds.
+func receiveEvent(t *testing.T, events <-chan interface{}) interface{} {
+	t.Helper()
+
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for voice event")
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+package dismock
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	// rtpHeaderSize is the size of the fixed RTP header.
+	rtpHeaderSize = 12
+	// rtpExtensionFlag is the flag set in the first byte of the RTP header,
+	// if the header is followed by a header extension.
+	rtpExtensionFlag = 0x10
+)
+
+// errDecryptionFailed is returned by openRTP, if a packet couldn't be
+// decrypted.
+var errDecryptionFailed = errors.New("dismock: failed to decrypt RTP packet")
+
+// openRTP decrypts the passed RTP packet, that was encrypted using the
+// passed encryption mode and secret key, and returns its opus payload.
+//
+// The header extension of the packet, if any, is stripped from the
+// payload.
+func openRTP(mode string, key *[32]byte, packet []byte) ([]byte, error) {
+	if len(packet) < rtpHeaderSize {
+		return nil, errDecryptionFailed
+	}
+
+	switch mode {
+	case "xsalsa20_poly1305", "xsalsa20_poly1305_suffix", "xsalsa20_poly1305_lite":
+		var nonce [24]byte
+
+		box := packet[rtpHeaderSize:]
+
+		switch mode {
+		case "xsalsa20_poly1305":
+			copy(nonce[:], packet[:rtpHeaderSize])
+		case "xsalsa20_poly1305_suffix":
+			if len(box) < len(nonce) {
+				return nil, errDecryptionFailed
+			}
+
+			copy(nonce[:], box[len(box)-len(nonce):])
+			box = box[:len(box)-len(nonce)]
+		case "xsalsa20_poly1305_lite":
+			if len(box) < 4 {
+				return nil, errDecryptionFailed
+			}
+
+			copy(nonce[:4], box[len(box)-4:])
+			box = box[:len(box)-4]
+		}
+
+		payload, ok := secretbox.Open(nil, box, &nonce, key)
+		if !ok {
+			return nil, errDecryptionFailed
+		}
+
+		// the header extension is encrypted as part of the payload
+		if packet[0]&rtpExtensionFlag != 0 {
+			return stripRTPExtension(payload)
+		}
+
+		return payload, nil
+	case "aead_aes256_gcm_rtpsize", "aead_xchacha20_poly1305_rtpsize":
+		aead, err := newRTPAEAD(mode, key)
+		if err != nil {
+			return nil, err
+		}
+
+		headerSize := rtpsizeHeaderSize(packet)
+		if len(packet) < headerSize+4 {
+			return nil, errDecryptionFailed
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		copy(nonce, packet[len(packet)-4:])
+
+		payload, err := aead.Open(nil, nonce, packet[headerSize:len(packet)-4], packet[:headerSize])
+		if err != nil {
+			return nil, errDecryptionFailed
+		}
+
+		// only the header of the extension is part of the unencrypted
+		// header, its body is encrypted as part of the payload
+		if packet[0]&rtpExtensionFlag != 0 {
+			extLen := 4 * int(binary.BigEndian.Uint16(packet[headerSize-2:headerSize]))
+			if len(payload) < extLen {
+				return nil, errDecryptionFailed
+			}
+
+			payload = payload[extLen:]
+		}
+
+		return payload, nil
+	default:
+		return nil, errors.New("dismock: unknown encryption mode " + mode)
+	}
+}
+
+// sealRTP encrypts the passed opus payload using the passed encryption mode
+// and secret key, and appends it to the passed RTP header.
+// The passed nonce is used by the modes, that use an incrementing nonce.
+func sealRTP(mode string, key *[32]byte, header, opus []byte, nonce uint32) ([]byte, error) {
+	switch mode {
+	case "xsalsa20_poly1305":
+		var n [24]byte
+		copy(n[:], header)
+
+		return secretbox.Seal(header, opus, &n, key), nil
+	case "xsalsa20_poly1305_suffix":
+		// the nonce doesn't need to be random, only unique
+		var n [24]byte
+		copy(n[:], header)
+		binary.BigEndian.PutUint32(n[20:], nonce)
+
+		return append(secretbox.Seal(header, opus, &n, key), n[:]...), nil
+	case "xsalsa20_poly1305_lite":
+		var n [24]byte
+		binary.BigEndian.PutUint32(n[:4], nonce)
+
+		return append(secretbox.Seal(header, opus, &n, key), n[:4]...), nil
+	case "aead_aes256_gcm_rtpsize", "aead_xchacha20_poly1305_rtpsize":
+		aead, err := newRTPAEAD(mode, key)
+		if err != nil {
+			return nil, err
+		}
+
+		n := make([]byte, aead.NonceSize())
+		binary.BigEndian.PutUint32(n, nonce)
+
+		return append(aead.Seal(header, n, opus, header), n[:4]...), nil
+	default:
+		return nil, errors.New("dismock: unknown encryption mode " + mode)
+	}
+}
+
+// newRTPAEAD creates the cipher.AEAD used by the passed AEAD encryption
+// mode.
+func newRTPAEAD(mode string, key *[32]byte) (cipher.AEAD, error) {
+	if mode == "aead_xchacha20_poly1305_rtpsize" {
+		return chacha20poly1305.NewX(key[:])
+	}
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// rtpsizeHeaderSize returns the size of the unencrypted header of the passed
+// packet, encrypted using one of the rtpsize modes.
+// It consists of the fixed header, the CSRCs and, if present, the header of
+// the header extension.
+func rtpsizeHeaderSize(packet []byte) int {
+	size := rtpHeaderSize + 4*int(packet[0]&0x0f)
+	if packet[0]&rtpExtensionFlag != 0 {
+		size += 4
+	}
+
+	return size
+}
+
+// stripRTPExtension strips the header extension from the passed decrypted
+// payload.
+func stripRTPExtension(payload []byte) ([]byte, error) {
+	if len(payload) < 4 {
+		return nil, errDecryptionFailed
+	}
+
+	extLen := 4 + 4*int(binary.BigEndian.Uint16(payload[2:4]))
+	if len(payload) < extLen {
+		return nil, errDecryptionFailed
+	}
+
+	return payload[extLen:], nil
+}
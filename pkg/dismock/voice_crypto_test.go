@@ -0,0 +1,61 @@
+package dismock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_openRTP(t *testing.T) {
+	key := [32]byte{1, 2, 3}
+
+	opus := []byte{0xf8, 0xff, 0xfe}
+
+	header := []byte{0x80, 0x78, 0, 1, 0, 0, 3, 0xc0, 0, 0, 0, 42}
+	// header with an extension containing a single element
+	extHeader := []byte{0x90, 0x78, 0, 1, 0, 0, 3, 0xc0, 0, 0, 0, 42}
+	ext := []byte{0xbe, 0xde, 0, 1, 0x10, 0xff, 0, 0}
+
+	for _, mode := range voiceModes {
+		mode := mode
+
+		t.Run(mode, func(t *testing.T) {
+			t.Run("success", func(t *testing.T) {
+				packet, err := sealRTP(mode, &key, append([]byte(nil), header...), opus, 1)
+				require.NoError(t, err)
+
+				actual, err := openRTP(mode, &key, packet)
+				require.NoError(t, err)
+				assert.Equal(t, opus, actual)
+			})
+
+			t.Run("extension", func(t *testing.T) {
+				var packet []byte
+				var err error
+
+				// the rtpsize modes leave the extension header unencrypted
+				if mode == "aead_aes256_gcm_rtpsize" || mode == "aead_xchacha20_poly1305_rtpsize" {
+					h := append(append([]byte(nil), extHeader...), ext[:4]...)
+					packet, err = sealRTP(mode, &key, h, append(append([]byte(nil), ext[4:]...), opus...), 1)
+				} else {
+					packet, err = sealRTP(mode, &key, append([]byte(nil), extHeader...),
+						append(append([]byte(nil), ext...), opus...), 1)
+				}
+				require.NoError(t, err)
+
+				actual, err := openRTP(mode, &key, packet)
+				require.NoError(t, err)
+				assert.Equal(t, opus, actual)
+			})
+
+			t.Run("failure", func(t *testing.T) {
+				packet, err := sealRTP(mode, &key, append([]byte(nil), header...), opus, 1)
+				require.NoError(t, err)
+
+				_, err = openRTP(mode, &[32]byte{4, 5, 6}, packet)
+				assert.Error(t, err)
+			})
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package dismock
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/voice"
+	"github.com/diamondburned/arikawa/v3/voice/voicegateway"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithVoice(t *testing.T) {
+	t.Run("arikawa", func(t *testing.T) {
+		m, s, vs := newVoiceSession(t, VoiceConfig{})
+
+		joinVoice(t, m, vs, 123, 456)
+
+		assert.Equal(t, voice.Protocol, m.Voice.Mode(123))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, vs.Speaking(ctx, voicegateway.Microphone))
+		m.Voice.WaitForSpeaking(123, voicegateway.Microphone)
+
+		m.Gateway.UpdateVoiceState(gateway.UpdateVoiceStateCommand{
+			GuildID:   123,
+			ChannelID: discord.ChannelID(discord.NullSnowflake),
+			SelfMute:  true,
+			SelfDeaf:  true,
+		})
+
+		require.NoError(t, vs.Leave(ctx))
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("requires gateway", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		done := make(chan struct{})
+
+		// Fatal stops the goroutine it was called in
+		go func() {
+			defer close(done)
+			New(tMock, WithVoice(VoiceConfig{}))
+		}()
+
+		<-done
+		assert.True(t, tMock.Failed())
+	})
+}
+
+func TestVoiceConfig_TrustViaSSLCertFile(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv("SSL_CERT_FILE", "abc")
+
+		m := New(t, WithGateway(GatewayConfig{}), WithVoice(VoiceConfig{}))
+
+		assert.Equal(t, "abc", os.Getenv("SSL_CERT_FILE"))
+
+		// the voice server can still be reached using its TLSConfig
+		c := dialVoice(t, m)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		if !sslCertFileSupported() {
+			t.Skip("SSL_CERT_FILE is not supported on " + runtime.GOOS)
+		}
+
+		t.Setenv("SSL_CERT_FILE", "abc")
+
+		var path string
+
+		t.Run("test", func(t *testing.T) {
+			New(t, WithGateway(GatewayConfig{}), WithVoice(VoiceConfig{TrustViaSSLCertFile: true}))
+
+			path = os.Getenv("SSL_CERT_FILE")
+			require.NotEqual(t, "abc", path)
+			require.FileExists(t, path)
+		})
+
+		assert.Equal(t, "abc", os.Getenv("SSL_CERT_FILE"))
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("rejected certificate", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}), WithVoice(VoiceConfig{}))
+		defer m.Close()
+
+		// don't use the system's root certificates, as another test may
+		// already have loaded them, while trusting the voice server
+		d := websocket.Dialer{TLSClientConfig: &tls.Config{RootCAs: x509.NewCertPool()}}
+
+		_, _, err := d.Dial("wss://"+m.Voice.Endpoint()+"/?v=4", nil)
+		require.Error(t, err)
+
+		assert.Eventually(t, tMock.Failed, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestVoiceGateway_heartbeat(t *testing.T) {
+	m := New(t, WithGateway(GatewayConfig{}), WithVoice(VoiceConfig{}))
+
+	c := dialVoice(t, m)
+	defer c.Close()
+
+	require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte(`{"op":3,"d":1234567890123456789}`)))
+
+	p := c.read(t)
+	assert.Equal(t, voiceHeartbeatAckOp, p.Op)
+	assert.Equal(t, "1234567890123456789", string(p.Data))
+}
+
+func TestVoiceGateway_handshake(t *testing.T) {
+	testCases := []struct {
+		name      string
+		identify  voicegateway.IdentifyCommand
+		mode      string
+		expectErr int
+	}{
+		{
+			name:     "success",
+			identify: voicegateway.IdentifyCommand{GuildID: 123, UserID: 1, SessionID: "abc", Token: "dismock"},
+			mode:     "aead_aes256_gcm_rtpsize",
+		},
+		{
+			name:      "invalid token",
+			identify:  voicegateway.IdentifyCommand{GuildID: 123, UserID: 1, SessionID: "abc", Token: "def"},
+			expectErr: voiceCloseAuthenticationError,
+		},
+		{
+			name:      "unknown guild",
+			identify:  voicegateway.IdentifyCommand{GuildID: 456, UserID: 1, SessionID: "abc", Token: "dismock"},
+			expectErr: voiceCloseServerNotFound,
+		},
+		{
+			name:      "invalid session",
+			identify:  voicegateway.IdentifyCommand{GuildID: 123, UserID: 1, SessionID: "def", Token: "dismock"},
+			expectErr: voiceCloseInvalidSession,
+		},
+		{
+			name:      "unknown mode",
+			identify:  voicegateway.IdentifyCommand{GuildID: 123, UserID: 1, SessionID: "abc", Token: "dismock"},
+			mode:      "plain",
+			expectErr: voiceCloseUnknownMode,
+		},
+	}
+
+	for _, c := range testCases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			tMock := new(testing.T)
+
+			m := New(tMock, WithGateway(GatewayConfig{User: discord.User{ID: 1}}), WithVoice(VoiceConfig{SSRC: 42}))
+			defer m.Close()
+
+			m.Voice.joins[123] = voiceJoin{channelID: 456, sessionID: "abc"}
+
+			conn := dialVoice(t, m)
+			defer conn.Close()
+
+			require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": voiceIdentifyOp, "d": c.identify}))
+
+			if c.mode != "" {
+				p := conn.read(t)
+				require.Equal(t, voiceReadyOp, p.Op)
+
+				var ready voicegateway.ReadyEvent
+				require.NoError(t, json.Unmarshal(p.Data, &ready))
+
+				assert.Equal(t, uint32(42), ready.SSRC)
+				assert.Equal(t, m.Voice.UDP.LocalAddr().String(), ready.Addr())
+				assert.Equal(t, voiceModes, ready.Modes)
+
+				require.NoError(t, conn.WriteJSON(map[string]interface{}{
+					"op": voiceSelectProtocolOp,
+					"d": voicegateway.SelectProtocolCommand{
+						Protocol: "udp",
+						Data:     voicegateway.SelectProtocolData{Address: "127.0.0.1", Port: 1234, Mode: c.mode},
+					},
+				}))
+			}
+
+			if c.expectErr == 0 {
+				p := conn.read(t)
+				require.Equal(t, voiceSessionDescriptionOp, p.Op)
+
+				var desc voicegateway.SessionDescriptionEvent
+				require.NoError(t, json.Unmarshal(p.Data, &desc))
+
+				assert.Equal(t, c.mode, desc.Mode)
+				assert.Equal(t, m.Voice.config.SecretKey, desc.SecretKey)
+				assert.Equal(t, c.mode, m.Voice.Mode(123))
+
+				assert.False(t, tMock.Failed())
+				return
+			}
+
+			_, _, err := conn.ReadMessage()
+
+			var closeErr *websocket.CloseError
+			require.True(t, errors.As(err, &closeErr), "unexpected error: %v", err)
+			assert.Equal(t, c.expectErr, closeErr.Code)
+
+			assert.True(t, tMock.Failed())
+		})
+	}
+}
+
+// newVoiceSession creates a new Mocker with a gateway and voice server, and
+// returns it along with a session connected to the gateway and a voice
+// session using that session.
+//
+// If arikawa can't be made to trust the voice server on the current
+// platform, the test is skipped.
+func newVoiceSession(t *testing.T, c VoiceConfig) (*Mocker, *session.Session, *voice.Session) {
+	t.Helper()
+
+	if !sslCertFileSupported() {
+		t.Skip("SSL_CERT_FILE is not supported on " + runtime.GOOS)
+	}
+
+	c.TrustViaSSLCertFile = true
+
+	user := discord.User{ID: 1, Username: "abc", Bot: true}
+
+	m, s := NewSession(t, WithGateway(GatewayConfig{User: user}), WithVoice(c))
+	openSession(t, s)
+
+	return m, s, voice.NewSessionCustom(s, user.ID)
+}
+
+// joinVoice makes the passed voice session join the voice channel with the
+// passed id.
+func joinVoice(t *testing.T, m *Mocker, vs *voice.Session, guildID discord.GuildID, channelID discord.ChannelID) {
+	t.Helper()
+
+	m.Channel(discord.Channel{ID: channelID, GuildID: guildID, Type: discord.GuildVoice})
+	m.Gateway.UpdateVoiceState(gateway.UpdateVoiceStateCommand{GuildID: guildID, ChannelID: channelID})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, vs.JoinChannel(ctx, channelID, false, false))
+}
+
+// dialVoice dials the voice server of the passed Mocker and reads the Hello
+// event.
+func dialVoice(t *testing.T, m *Mocker) *testGatewayConn {
+	t.Helper()
+
+	d := websocket.Dialer{TLSClientConfig: m.Voice.TLSConfig()}
+
+	conn, _, err := d.Dial("wss://"+m.Voice.Endpoint()+"/?v=4", nil)
+	require.NoError(t, err)
+
+	c := &testGatewayConn{Conn: conn}
+
+	p := c.read(t)
+	require.Equal(t, voiceHelloOp, p.Op)
+
+	return c
+}
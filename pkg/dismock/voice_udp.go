@@ -0,0 +1,177 @@
+package dismock
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// VoicePacket is a decrypted RTP packet sent to the voice server.
+type VoicePacket struct {
+	// SSRC is the SSRC of the packet.
+	SSRC uint32
+	// Sequence is the sequence number of the packet.
+	Sequence uint16
+	// Timestamp is the RTP timestamp of the packet.
+	Timestamp uint32
+	// Opus is the decrypted opus frame contained in the packet.
+	Opus []byte
+}
+
+const (
+	// ipDiscoveryRequest is the type of IP discovery requests.
+	ipDiscoveryRequest = 0x1
+	// ipDiscoveryResponse is the type of IP discovery responses.
+	ipDiscoveryResponse = 0x2
+	// ipDiscoveryLength is the length of IP discovery packets, excluding
+	// the type and length fields.
+	ipDiscoveryLength = 70
+)
+
+// serveUDP serves the UDP server of the VoiceGateway, until its connection
+// is closed.
+func (v *VoiceGateway) serveUDP() {
+	defer v.wg.Done()
+
+	buf := make([]byte, 1500)
+
+	for {
+		n, addr, err := v.UDP.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case isRTP(buf[:n]):
+			v.capture(buf[:n], addr)
+		case isIPDiscovery(buf[:n]):
+			v.answerIPDiscovery(buf[:n], addr)
+		}
+	}
+}
+
+// isRTP checks if the passed packet is an RTP packet.
+// RTCP packets, which are multiplexed with RTP packets, are not considered
+// RTP packets.
+func isRTP(packet []byte) bool {
+	if len(packet) < rtpHeaderSize || packet[0]&0xc0 != 0x80 {
+		return false
+	}
+
+	// RTCP packet types are in the range 192-223, which corresponds to RTP
+	// payload types 64-95 with the marker bit set
+	return packet[1] < 192 || packet[1] > 223
+}
+
+// capture decrypts the passed RTP packet, sent from the passed address,
+// using the encryption mode of the voice session it belongs to, and adds it
+// to the packets of that session.
+func (v *VoiceGateway) capture(packet []byte, addr *net.UDPAddr) {
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	s := v.sessionBySSRC(ssrc)
+	if s == nil {
+		v.t.Errorf("dismock: received RTP packet with unknown ssrc %d", ssrc)
+		return
+	} else if s.mode == "" {
+		v.t.Errorf("dismock: received RTP packet with ssrc %d before an encryption mode was selected", ssrc)
+		return
+	}
+
+	opus, err := openRTP(s.mode, &v.config.SecretKey, packet)
+	if err != nil {
+		v.t.Errorf("dismock: failed to decrypt RTP packet with ssrc %d using %s: %s", ssrc, s.mode, err)
+		return
+	}
+
+	s.addr = addr
+	s.packets = append(s.packets, VoicePacket{
+		SSRC:      ssrc,
+		Sequence:  binary.BigEndian.Uint16(packet[2:4]),
+		Timestamp: binary.BigEndian.Uint32(packet[4:8]),
+		Opus:      append([]byte(nil), opus...),
+	})
+	v.notify()
+}
+
+// isIPDiscovery checks if the passed packet is an IP discovery request.
+//
+// Besides requests as documented by Discord, requests with another type
+// that are missing the address and port fields, as sent by some clients,
+// are accepted as well.
+func isIPDiscovery(packet []byte) bool {
+	if len(packet) < 8 || binary.BigEndian.Uint16(packet[2:4]) != ipDiscoveryLength {
+		return false
+	}
+
+	return binary.BigEndian.Uint16(packet[0:2]) == ipDiscoveryRequest || len(packet) == ipDiscoveryLength
+}
+
+// sessionBySSRC returns the voice session with the passed ssrc, or nil if
+// there is none.
+// mut must be locked.
+func (v *VoiceGateway) sessionBySSRC(ssrc uint32) *voiceSession {
+	for _, s := range v.sessions {
+		if s.ssrc == ssrc {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// answerIPDiscovery answers the passed IP discovery request, sent from the
+// passed address.
+// The address is remembered as the address of the voice session with the
+// ssrc of the request, so that audio can be sent to it.
+func (v *VoiceGateway) answerIPDiscovery(request []byte, addr *net.UDPAddr) {
+	v.mut.Lock()
+	if s := v.sessionBySSRC(binary.BigEndian.Uint32(request[4:8])); s != nil {
+		s.addr = addr
+		v.notify()
+	}
+	v.mut.Unlock()
+
+	var resp [4 + ipDiscoveryLength]byte
+
+	binary.BigEndian.PutUint16(resp[0:2], ipDiscoveryResponse)
+	binary.BigEndian.PutUint16(resp[2:4], ipDiscoveryLength)
+	copy(resp[4:8], request[4:8]) // ssrc
+	copy(resp[8:71], addr.IP.String())
+	binary.BigEndian.PutUint16(resp[72:74], uint16(addr.Port))
+
+	_, _ = v.UDP.WriteToUDP(resp[:], addr)
+}
+
+// Packets returns the RTP packets the client sent for its voice connection
+// in the guild with the passed id, in the order they were received.
+func (v *VoiceGateway) Packets(guildID discord.GuildID) []VoicePacket {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	if s := v.sessions[guildID]; s != nil {
+		return append([]VoicePacket(nil), s.packets...)
+	}
+
+	return nil
+}
+
+// WaitForPackets waits until the client sent at least n RTP packets for its
+// voice connection in the guild with the passed id, and returns them.
+// If the Timeout of the VoiceConfig passes before that, the test fails, and
+// the packets received until then are returned.
+func (v *VoiceGateway) WaitForPackets(guildID discord.GuildID, n int) []VoicePacket {
+	ok := v.waitFor(func() bool {
+		s := v.sessions[guildID]
+		return s != nil && len(s.packets) >= n
+	})
+	if !ok {
+		v.t.Errorf("dismock: timed out waiting for %d RTP packets in guild %d", n, guildID)
+	}
+
+	return v.Packets(guildID)
+}
@@ -0,0 +1,75 @@
+package dismock
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoiceGateway_Packets(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, s, vs := newVoiceSession(t, VoiceConfig{})
+
+		joinVoice(t, m, vs, 123, 456)
+
+		frames := [][]byte{{0xf8, 0xff, 0xfe}, {1, 2, 3, 4}, {5}}
+
+		for _, f := range frames {
+			_, err := vs.Write(f)
+			require.NoError(t, err)
+		}
+
+		packets := m.Voice.WaitForPackets(123, len(frames))
+		require.Len(t, packets, len(frames))
+
+		for i, p := range packets {
+			assert.Equal(t, uint32(1), p.SSRC)
+			assert.Equal(t, frames[i], p.Opus)
+
+			if i > 0 {
+				assert.Equal(t, packets[i-1].Sequence+1, p.Sequence)
+				assert.Equal(t, packets[i-1].Timestamp+960, p.Timestamp)
+			}
+		}
+
+		m.Gateway.UpdateVoiceState(gateway.UpdateVoiceStateCommand{
+			GuildID:   123,
+			ChannelID: discord.ChannelID(discord.NullSnowflake),
+			SelfMute:  true,
+			SelfDeaf:  true,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, vs.Leave(ctx))
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("unknown ssrc", func(t *testing.T) {
+		tMock := new(testing.T)
+
+		m := New(tMock, WithGateway(GatewayConfig{}), WithVoice(VoiceConfig{Timeout: 100 * time.Millisecond}))
+		defer m.Close()
+
+		conn, err := net.DialUDP("udp", nil, m.Voice.UDP.LocalAddr().(*net.UDPAddr))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		packet := []byte{0x80, 0x78, 0, 1, 0, 0, 3, 0xc0, 0, 0, 0, 0, 1, 2, 3}
+		binary.BigEndian.PutUint32(packet[8:12], 42)
+
+		_, err = conn.Write(packet)
+		require.NoError(t, err)
+
+		m.Voice.WaitForPackets(123, 1)
+		assert.True(t, tMock.Failed())
+	})
+}